@@ -0,0 +1,178 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"wc/count"
+)
+
+// tarMagicOffset is where the magic of a POSIX or GNU tar header starts
+const tarMagicOffset = 257
+
+// recorder keeps the bytes read while detecting an archive, so an input
+// that is not an archive can be counted from its start
+type recorder struct {
+	buf bytes.Buffer
+	off bool
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if !r.off {
+		r.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// archiveFormat is an archive format detected by its magic bytes
+type archiveFormat int
+
+const (
+	archiveNone archiveFormat = iota
+	archiveTar
+	archiveZip
+)
+
+// sniffArchive detects a zip archive or a tar archive, compressed or not,
+// at the start of r. It returns the content of a tar archive, or a reader
+// of r from its start for the other inputs.
+func sniffArchive(r io.Reader) (archiveFormat, io.Reader) {
+	rec := &recorder{}
+	raw := bufio.NewReaderSize(io.TeeReader(r, rec), count.BufferSize)
+	fromStart := func() io.Reader {
+		rec.off = true
+		return io.MultiReader(&rec.buf, r)
+	}
+
+	magic, _ := raw.Peek(4)
+	if bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")) {
+		return archiveZip, fromStart()
+	}
+
+	stream, err := decompress(raw)
+	if err != nil {
+		// counted as it is, decompressing it fails again with -z
+		return archiveNone, fromStart()
+	}
+	content := bufio.NewReaderSize(stream, count.BufferSize)
+	header, _ := content.Peek(tarMagicOffset + 5)
+	if bytes.HasPrefix(header[min(len(header), tarMagicOffset):], []byte("ustar")) {
+		rec.off = true
+		return archiveTar, content
+	}
+	return archiveNone, fromStart()
+}
+
+// archiveCounts holds the rows of the files of an archive and their total,
+// whose compressed size is the size of the archive
+type archiveCounts struct {
+	total   counts
+	members []result
+}
+
+// countArchive counts every regular file of a tar or zip archive. It
+// returns nil, with a reader of r from its start, when r is not an archive.
+// A zip archive can only be read from file.
+func countArchive(name string, r io.Reader, file *os.File, opts *options) (*archiveCounts, io.Reader, error) {
+	raw := &countingReader{r: r}
+	format, content := sniffArchive(raw)
+
+	a := &archiveCounts{}
+	var err error
+	switch format {
+	case archiveTar:
+		err = a.countTar(name, tar.NewReader(content), opts)
+		if err == nil {
+			// the end of the archive may be followed by padding
+			_, err = io.Copy(io.Discard, raw)
+		}
+		a.total.compressed = raw.n
+	case archiveZip:
+		if file == nil {
+			return a, nil, errors.New("a zip archive cannot be read from a stream")
+		}
+		err = a.countZip(name, file, opts)
+	default:
+		return nil, content, nil
+	}
+	return a, nil, err
+}
+
+// countTar counts the regular files of a tar archive
+func (a *archiveCounts) countTar(name string, archive *tar.Reader, opts *options) error {
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := a.add(name, header.Name, archive, opts); err != nil {
+			return err
+		}
+	}
+}
+
+// countZip counts the regular files of a zip archive
+func (a *archiveCounts) countZip(name string, file *os.File, opts *options) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	defer func() { a.total.compressed = info.Size() }()
+	archive, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range archive.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		member, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = a.add(name, f.Name, member, opts)
+		closeErr := member.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+	}
+	return nil
+}
+
+// add counts the file path of the archive name. An error reading the file is
+// an error of the archive.
+func (a *archiveCounts) add(name, path string, r io.Reader, opts *options) error {
+	var member counts
+	var err error
+	copts := opts.countOptions(path)
+	if opts.decompress {
+		member, err = countCompressed(r, copts)
+	} else {
+		member, err = countReader(r, copts)
+	}
+	if err != nil {
+		return err
+	}
+	member.language = opts.languageOf(path)
+
+	a.total.add(member)
+	// the words are only needed in the total of the archive
+	member.words = nil
+	a.members = append(a.members, result{name: name + ":" + path, counts: member, member: true})
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// archiveFiles are the files of the test archives, in order
+var archiveFiles = [][2]string{
+	{"src/a.txt", "hello world\n"},
+	{"src/b.go", "package main\n// x\n"},
+}
+
+func tarContent(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	if err := w.WriteHeader(&tar.Header{Name: "src/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range archiveFiles {
+		if err := w.WriteHeader(&tar.Header{Name: file[0], Mode: 0o644, Size: int64(len(file[1]))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipContent(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, file := range archiveFiles {
+		f, err := w.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRunArchive(t *testing.T) {
+	dir := t.TempDir()
+	tgz := gzipContent(t, string(tarContent(t)))
+	archives := map[string][]byte{
+		"rel.tar":    tarContent(t),
+		"rel.tar.gz": tgz,
+		"rel.zip":    zipContent(t),
+		"notes.txt":  []byte("not an archive\n"),
+	}
+	for name, content := range archives {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	got := runOutput(t, "-l", "-w", "--archive", path("rel.tar.gz"), path("rel.zip"), path("notes.txt"))
+	// the size of the archives says nothing of the counts of their files
+	width := 7
+	var want strings.Builder
+	for _, archive := range []string{"rel.tar.gz", "rel.zip"} {
+		fmt.Fprintf(&want, "%*d %*d %s:src/a.txt\n", width, 1, width, 2, path(archive))
+		fmt.Fprintf(&want, "%*d %*d %s:src/b.go\n", width, 2, width, 4, path(archive))
+		fmt.Fprintf(&want, "%*d %*d %s\n", width, 3, width, 6, path(archive))
+	}
+	fmt.Fprintf(&want, "%*d %*d %s\n", width, 1, width, 3, path("notes.txt"))
+	fmt.Fprintf(&want, "%*d %*d total\n", width, 7, width, 15)
+	if got != want.String() {
+		t.Errorf("--archive output = %q, want %q", got, want.String())
+	}
+
+	// the compressed size of a tar archive is the size of the archive
+	got = runOutput(t, "-l", "-z", "--archive", "--total=never", path("rel.tar.gz"))
+	if want := fmt.Sprintf("%7d %7d %s\n", 3, len(tgz), path("rel.tar.gz")); !strings.HasSuffix(got, want) {
+		t.Errorf("-z --archive output = %q, want it to end with %q", got, want)
+	}
+
+	if got, want := runWithStdin(t, string(tarContent(t)), "-l", "--archive"), "1 -:src/a.txt\n2 -:src/b.go\n3\n"; got != want {
+		t.Errorf("standard input output = %q, want %q", got, want)
+	}
+	if got, want := runWithStdin(t, "plain text\n", "-w", "--archive"), "2\n"; got != want {
+		t.Errorf("standard input output = %q, want %q", got, want)
+	}
+}
+
+func TestRunArchiveJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rel.tar.gz")
+	if err := os.WriteFile(path, gzipContent(t, string(tarContent(t))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	paths := writeTestFiles(t, "hello\n")
+
+	var stdout bytes.Buffer
+	args := []string{"--archive", "--format=json", path, paths[0], filepath.Join(dir, "missing")}
+	if err := run(args, nil, &stdout, io.Discard); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	var got jsonOutput
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+	}
+	// the members are counted in the archive
+	if got.Total["files"] != 2.0 || got.Total["errors"] != 1.0 {
+		t.Errorf("total = %v, want 2 files and 1 error", got.Total)
+	}
+}
+
+func TestRunArchiveErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"-l", "--archive"}, bytes.NewReader(zipContent(t)), &stdout, &stderr)
+	if err == nil {
+		t.Error("Expected an error for a zip archive read from standard input")
+	}
+	if want := "wc: standard input: a zip archive cannot be read from a stream\n"; stderr.String() != want {
+		t.Errorf("errors = %q, want %q", stderr.String(), want)
+	}
+
+	// members counted before a corrupt member are still printed
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.tar")
+	content := tarContent(t)
+	if err := os.WriteFile(path, content[:1024+512+4], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	stderr.Reset()
+	if err := run([]string{"-l", "--archive", path}, nil, &stdout, &stderr); err == nil {
+		t.Error("Expected an error for a truncated tar archive")
+	}
+	if !strings.HasPrefix(stdout.String(), "1 "+path+":src/a.txt\n") {
+		t.Errorf("output = %q, want the first member", stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "wc: "+path+": ") {
+		t.Errorf("errors = %q, want an error of %s", stderr.String(), path)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"wc/count"
+)
+
+// cacheVersion changes with the format of the cache or when the counts of a
+// file change with the same options, older caches are discarded
+const cacheVersion = 1
+
+// racyAge is how long after its modification a file is not cached: a file
+// may be written again within the resolution of its modification time,
+// which is 2 seconds on some file systems, without any change to its identity
+const racyAge = 2 * time.Second
+
+// cacheOptions are the options that change the counts of a file, a cache
+// saved with other options is discarded
+type cacheOptions struct {
+	Version    int
+	Invalid    count.InvalidPolicy
+	Graphemes  bool
+	Words      count.WordRule
+	EOL        count.EOL
+	Encoding   count.Encoding
+	Pattern    string
+	Code       bool
+	Decompress bool
+	Recursive  bool // binary files are skipped
+	LinesOnly  bool
+	NoWidth    bool
+}
+
+// cacheOptions returns the options of the counts saved in the cache
+func (o *options) cacheOptions() cacheOptions {
+	opts := cacheOptions{
+		Version:    cacheVersion,
+		Invalid:    o.count.Invalid,
+		Graphemes:  o.count.Graphemes,
+		Words:      o.count.Words,
+		EOL:        o.count.EOL,
+		Encoding:   o.count.Encoding,
+		Code:       o.code,
+		Decompress: o.decompress,
+		Recursive:  o.walk.recursive,
+		LinesOnly:  o.count.LinesOnly,
+		NoWidth:    o.count.NoWidth,
+	}
+	if o.count.Pattern != nil {
+		opts.Pattern = o.count.Pattern.String()
+	}
+	return opts
+}
+
+// cacheEntry is the identity of a file when it was counted and its counts.
+// A file with the same path, size, modification time and inode is not read
+// again.
+type cacheEntry struct {
+	Size       int64
+	ModTime    int64 // nanoseconds since the epoch
+	Inode      uint64
+	Counts     count.Result
+	Compressed int64
+}
+
+func newCacheEntry(info fs.FileInfo) cacheEntry {
+	return cacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Inode: inode(info)}
+}
+
+// sameFile reports whether e and other are the same file with the same
+// content
+func (e cacheEntry) sameFile(other cacheEntry) bool {
+	return e.Size == other.Size && e.ModTime == other.ModTime && e.Inode == other.Inode
+}
+
+// cacheFile is the content of the file of the cache
+type cacheFile struct {
+	Options cacheOptions
+	Files   map[string]cacheEntry // by absolute path
+}
+
+// countCache holds the counts of the files of the previous run, with
+// --cache. It only keeps the files counted by the current run, found in the
+// cache or not.
+type countCache struct {
+	filename string
+	options  cacheOptions
+	start    time.Time // files modified shortly before are not cached
+	mu       sync.Mutex
+	before   map[string]cacheEntry
+	after    map[string]cacheEntry
+}
+
+// openCache reads the cache saved to filename. A missing or unreadable
+// cache, or one saved with other options, is empty.
+func openCache(filename string, options cacheOptions) (c *countCache, err error) {
+	c = &countCache{
+		filename: filename,
+		options:  options,
+		start:    time.Now(),
+		before:   make(map[string]cacheEntry),
+		after:    make(map[string]cacheEntry),
+	}
+
+	file, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile(file, &err)
+
+	var saved cacheFile
+	if err := gob.NewDecoder(file).Decode(&saved); err == nil && saved.Options == options {
+		c.before = saved.Files
+	}
+	return c, nil
+}
+
+// count returns the counts of file from the cache, or counts it and caches
+// its counts when it did not change while it was read
+func (c *countCache) count(filename string, file *os.File, opts *options) (counts, []result, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return counts{}, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return counts{}, nil, err
+	}
+
+	entry := newCacheEntry(info)
+	c.mu.Lock()
+	cached, ok := c.before[path]
+	if ok && cached.sameFile(entry) {
+		c.after[path] = cached
+	}
+	c.mu.Unlock()
+	if ok && cached.sameFile(entry) {
+		counted := counts{Result: cached.Counts, compressed: cached.Compressed}
+		counted.language = opts.languageOf(filename)
+		return counted, nil, nil
+	}
+
+	counted, members, err := countOpened(filename, file, opts)
+	if err != nil || c.start.Sub(info.ModTime()) < racyAge {
+		return counted, members, err
+	}
+	if info, err := file.Stat(); err != nil || !newCacheEntry(info).sameFile(entry) {
+		// changed while counted
+		return counted, members, nil
+	}
+	entry.Counts = counted.Result
+	entry.Compressed = counted.compressed
+	c.mu.Lock()
+	c.after[path] = entry
+	c.mu.Unlock()
+	return counted, members, nil
+}
+
+// save writes the files of the run to the cache. The cache is replaced at
+// once, so a concurrent run reads either the old or the new one.
+func (c *countCache) save() (err error) {
+	temp, err := os.CreateTemp(filepath.Dir(c.filename), filepath.Base(c.filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(temp.Name())
+		}
+	}()
+
+	err = gob.NewEncoder(temp).Encode(cacheFile{Options: c.options, Files: c.after})
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), c.filename)
+}
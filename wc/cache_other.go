@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "io/fs"
+
+// inode returns 0, the files are only identified by their path, size and
+// modification time
+func inode(fs.FileInfo) uint64 {
+	return 0
+}
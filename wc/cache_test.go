@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// rewrite replaces the content of name, keeping its modification time
+func rewrite(t *testing.T, name, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	paths := writeTestFiles(t, "one two\n", "three\n")
+	old := time.Now().Add(-time.Hour)
+	for _, name := range paths {
+		if err := os.Chtimes(name, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache := filepath.Join(t.TempDir(), "counts.cache")
+	runOutput(t, "-w", "--cache", cache, paths[0], paths[1])
+
+	// a file with the same identity is not read again
+	rewrite(t, paths[0], "one-two\n", old)
+	if got, want := runOutput(t, "-w", "--cache", cache, paths[0]), "2 "+paths[0]+"\n"; got != want {
+		t.Errorf("cached output = %q, want %q", got, want)
+	}
+	if got, want := runOutput(t, "-w", "--cache", cache, "--no-cache", paths[0]), "1 "+paths[0]+"\n"; got != want {
+		t.Errorf("--no-cache output = %q, want %q", got, want)
+	}
+	// other options discard the cache
+	if got, want := runOutput(t, "-w", "--words=posix", "--cache", cache, paths[0]), "1 "+paths[0]+"\n"; got != want {
+		t.Errorf("output with other options = %q, want %q", got, want)
+	}
+	runOutput(t, "-w", "--cache", cache, paths[0])
+	rewrite(t, paths[0], "1 2 3 4\n", old.Add(time.Second))
+	if got, want := runOutput(t, "-w", "--cache", cache, paths[0]), "4 "+paths[0]+"\n"; got != want {
+		t.Errorf("output of a modified file = %q, want %q", got, want)
+	}
+}
+
+func TestRunCacheRecentFiles(t *testing.T) {
+	paths := writeTestFiles(t, "one two\n")
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(t.TempDir(), "counts.cache")
+	runOutput(t, "-w", "--cache", cache, paths[0])
+
+	// written again within the resolution of the modification time
+	rewrite(t, paths[0], "one-two\n", info.ModTime())
+	if got, want := runOutput(t, "-w", "--cache", cache, paths[0]), "1 "+paths[0]+"\n"; got != want {
+		t.Errorf("output of a recent file = %q, want %q", got, want)
+	}
+}
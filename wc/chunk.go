@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+
+	"wc/count"
+)
+
+// minChunkSize is the smallest byte range a file is split into, smaller
+// files are not worth the extra goroutines
+var minChunkSize int64 = 4 << 20
+
+// countOpenFile counts an open file, splitting regular files into chunks
+// counted concurrently when chunks is more than one
+func countOpenFile(file *os.File, chunks int, opts count.Options) (counts, error) {
+	if chunks > 1 {
+		info, err := file.Stat()
+		if err != nil {
+			return counts{}, err
+		}
+		if info.Mode().IsRegular() && info.Size() >= 2*minChunkSize {
+			n := min(int64(chunks), info.Size()/minChunkSize)
+			c, err := count.CounterAt(file, info.Size(), int(n), opts)
+			if err != nil {
+				return counts{}, err
+			}
+			return countsOf(c), nil
+		}
+	}
+
+	return countReader(file, opts)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCountOpenFileChunks(t *testing.T) {
+	previous := minChunkSize
+	minChunkSize = 1
+	t.Cleanup(func() { minChunkSize = previous })
+
+	content := "héllo\twörld 日本語\n" + strings.Repeat("a few words per line\n", 50)
+	paths := writeTestFiles(t, content)
+
+	for _, chunks := range []int{1, 2, 7, 16} {
+		file, err := os.Open(paths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := countOpenFile(file, chunks, defaultOptions)
+		closeFile(file, &err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := countContent([]byte(content)); got != want {
+			t.Errorf("countOpenFile() with %d chunks = %+v, want %+v", chunks, got, want)
+		}
+		if int(got.Bytes) != countBytes([]byte(content)) || int(got.Lines) != countLines([]byte(content)) ||
+			int(got.Words) != countWords([]byte(content)) {
+			t.Errorf("countOpenFile() with %d chunks = %+v, want %d bytes, %d lines and %d words", chunks, got,
+				countBytes([]byte(content)), countLines([]byte(content)), countWords([]byte(content)))
+		}
+	}
+}
+
+func TestRunChunksMatchesSerial(t *testing.T) {
+	previous := minChunkSize
+	minChunkSize = 64
+	t.Cleanup(func() { minChunkSize = previous })
+
+	paths := writeTestFiles(t, strings.Repeat("héllo\twörld 日本語\n", 500))
+
+	serial := runOutput(t, "-l", "-w", "-m", "-c", "--longest-line", paths[0])
+	chunked := runOutput(t, "--chunks", "8", "-l", "-w", "-m", "-c", "--longest-line", paths[0])
+	if serial != chunked {
+		t.Errorf("chunked output = %q, want %q", chunked, serial)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"maps"
+	"slices"
+
+	"wc/count"
+)
+
+// codeColumns are the lines of source code by their content and the
+// language of the file, printed with --code
+var codeColumns = []column{
+	{"code", func(c counts) int64 { return c.Code }, nil, false},
+	{"comment", func(c counts) int64 { return c.Comment }, nil, false},
+	{"blank", func(c counts) int64 { return c.Blank }, nil, false},
+	{"language", nil, func(c counts) string { return c.language }, false},
+}
+
+// allLanguages is the language of a total of files in several languages
+const allLanguages = "all"
+
+// countOptions returns the options counting filename, with --code its lines
+// are classified in the language of its extension
+func (o *options) countOptions(filename string) count.Options {
+	opts := o.count
+	if o.code {
+		opts.Language = count.LanguageOf(filename)
+	}
+	return opts
+}
+
+// languageOf returns the language of filename printed with --code
+func (o *options) languageOf(filename string) string {
+	if !o.code {
+		return ""
+	}
+	return count.LanguageOf(filename).Name
+}
+
+// languageTotals accumulates the counts of the files of each language
+type languageTotals map[string]*counts
+
+func (t languageTotals) add(c counts) {
+	if c.language == "" {
+		return
+	}
+	total, ok := t[c.language]
+	if !ok {
+		total = &counts{}
+		t[c.language] = total
+	}
+	total.add(c)
+}
+
+// report prints the total of every language by name, unless there is only
+// one and it is the same as the total
+func (t languageTotals) report(rep reporter) {
+	if len(t) < 2 {
+		return
+	}
+	for _, language := range slices.Sorted(maps.Keys(t)) {
+		rep.languageTotal(*t[language])
+	}
+}
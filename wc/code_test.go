@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCode(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":  "package main\n\n// main runs\nfunc main() {}\n",
+		"util.go":  "/*\nutil\n*/\nvar s = \"// no\"\n",
+		"tool.py":  "# tool\n\"\"\"Doc\"\"\"\nx = '#'\n",
+		"notes.md": "# Notes\n<!-- todo -->\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	got := runOutput(t, "-l", "--code", path("main.go"), path("util.go"), path("tool.py"))
+	want := " 4  2  1  1 Go " + path("main.go") + "\n" +
+		" 4  1  3  0 Go " + path("util.go") + "\n" +
+		" 3  1  2  0 Python " + path("tool.py") + "\n" +
+		" 8  3  4  1 Go total\n" +
+		" 3  1  2  0 Python total\n" +
+		"11  4  6  1 all total\n"
+	if got != want {
+		t.Errorf("--code output = %q, want %q", got, want)
+	}
+
+	// a single language has no total of its own
+	got = runOutput(t, "-l", "--code", path("main.go"), path("util.go"))
+	want = " 4  2  1  1 Go " + path("main.go") + "\n" +
+		" 4  1  3  0 Go " + path("util.go") + "\n" +
+		" 8  3  4  1 Go total\n"
+	if got != want {
+		t.Errorf("--code output = %q, want %q", got, want)
+	}
+
+	got = runOutput(t, "-l", "--code", "--format=csv", path("notes.md"), path("tool.py"))
+	want = "path,lines,words,chars,bytes,code,comment,blank,language,error\n" +
+		path("notes.md") + ",2,5,22,22,1,1,0,Markdown,\n" +
+		path("tool.py") + ",3,6,25,25,1,2,0,Python,\n" +
+		"total,2,5,22,22,1,1,0,Markdown,\n" +
+		"total,3,6,25,25,1,2,0,Python,\n" +
+		"total,5,11,47,47,2,3,0,all,\n"
+	if got != want {
+		t.Errorf("--format=csv output = %q, want %q", got, want)
+	}
+
+	got = runOutput(t, "-l", "--code", "--format=json", "--total=only", path("notes.md"), path("tool.py"))
+	// only the grand total, without the totals of the languages
+	want = `{"files":[],"total":{"lines":5,"words":11,"chars":47,"bytes":47,"code":2,"comment":3,"blank":0,"language":"all","files":2,"errors":0}}` + "\n"
+	if got != want {
+		t.Errorf("--format=json output = %q, want %q", got, want)
+	}
+
+	got = runOutput(t, "-l", "--code", "--total=only", path("main.go"), path("util.go"), path("tool.py"))
+	if want := "11 4 6 1 all\n"; got != want {
+		t.Errorf("--total=only output = %q, want %q", got, want)
+	}
+
+	if got, want := runWithStdin(t, "x\n\n", "-l", "--code"), "      2       1       0       1 Text\n"; got != want {
+		t.Errorf("standard input output = %q, want %q", got, want)
+	}
+}
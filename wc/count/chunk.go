@@ -0,0 +1,109 @@
+package count
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// ReaderAt counts size bytes of r by splitting them into up to n byte ranges
+// counted concurrently. Ranges start at a rune, or at a line when the options
+// are LineAligned, so merging their counters in order gives the same counts
+// as a single pass. Inputs are counted in a single range when the options
+// are not Splittable.
+func ReaderAt(r io.ReaderAt, size int64, n int, opts Options) (Result, error) {
+	c, err := CounterAt(r, size, n, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	return c.Result(), nil
+}
+
+// CounterAt is like ReaderAt but returns the merged counter, which also holds
+// the word frequencies
+func CounterAt(r io.ReaderAt, size int64, n int, opts Options) (*Counter, error) {
+	if !opts.Splittable() {
+		n = 1
+	}
+	offsets, err := chunkOffsets(r, size, n, opts.LineAligned())
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make([]*Counter, len(offsets)-1)
+	errs := make([]error, len(counters))
+	var wg sync.WaitGroup
+	for i := range counters {
+		counters[i] = NewCounter(opts)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			section := io.NewSectionReader(r, offsets[i], offsets[i+1]-offsets[i])
+			_, errs[i] = counters[i].ReadFrom(section)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	c := counters[0]
+	for _, next := range counters[1:] {
+		c.Merge(next)
+	}
+
+	return c, nil
+}
+
+// chunkOffsets splits size bytes into at most n non-empty ranges, it returns
+// the start of every range followed by size
+func chunkOffsets(r io.ReaderAt, size int64, n int, lineAligned bool) ([]int64, error) {
+	ranges := max(min(int64(n), size), 1)
+
+	offsets := []int64{0}
+	for i := int64(1); i < ranges; i++ {
+		target := size * i / ranges
+		if target <= offsets[len(offsets)-1] {
+			// the previous range already extends past this one
+			continue
+		}
+		offset, err := nextBoundary(r, target, size, lineAligned)
+		if err != nil {
+			return nil, err
+		}
+		if offset >= size {
+			break
+		}
+		offsets = append(offsets, offset)
+	}
+
+	return append(offsets, size), nil
+}
+
+// nextBoundary returns the first offset at or after from where a range can
+// start: the start of a rune, or the start of a line when lineAligned. It
+// returns size when there is none.
+func nextBoundary(r io.ReaderAt, from, size int64, lineAligned bool) (int64, error) {
+	buf := make([]byte, 4096)
+	for offset := from; offset < size; {
+		n, err := r.ReadAt(buf, offset)
+		for i, b := range buf[:n] {
+			switch {
+			case lineAligned && b == '\n':
+				return offset + int64(i) + 1, nil
+			case !lineAligned && utf8.RuneStart(b):
+				return offset + int64(i), nil
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(n)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,137 @@
+package count
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// chunkCorpus is the content used by the other tests, plus text that puts
+// multi-byte runes, tabs and carriage returns around every boundary
+func chunkCorpus(t *testing.T) map[string][]byte {
+	t.Helper()
+	lorum, err := os.ReadFile("../lorum.txt")
+	if err != nil {
+		t.Fatalf("Failed to read lorum.txt: %v", err)
+	}
+
+	return map[string][]byte{
+		"lorum":       lorum,
+		"integration": []byte("hello world\nthis is a test\nwith multiple lines"),
+		"edge cases":  []byte(strings.Repeat("hello world\n", 1000)),
+		"whitespace":  []byte("   \t\n   "),
+		"unicode":     []byte("café naïve\t日本語\tテキスト\n\U0001F600 smile\r\ncafé\t\tend"),
+		"invalid":     []byte("abc \xe2\x82 def\xff\n\xf0\x9f\x98 x\t\xe2"),
+		"tabs":        []byte("a\tb\tc\n\t\t\tlong\ttabbed\tline\nx\ty\r\tz\n"),
+	}
+}
+
+func TestCounterMergeMatchesSerial(t *testing.T) {
+	for name, content := range chunkCorpus(t) {
+		t.Run(name, func(t *testing.T) {
+			want := Bytes(content, defaultOptions)
+			// large contents are only split every few bytes
+			step := max(len(content)/500, 1)
+			for split := 0; split <= len(content); split += step {
+				if split < len(content) && !utf8.RuneStart(content[split]) {
+					continue
+				}
+
+				left := NewCounter(defaultOptions)
+				_, _ = left.Write(content[:split])
+				right := NewCounter(defaultOptions)
+				_, _ = right.Write(content[split:])
+				left.Merge(right)
+
+				if got := left.Result(); got != want {
+					t.Fatalf("merge at %d = %+v, want %+v", split, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCounterMergeThreeParts(t *testing.T) {
+	content := chunkCorpus(t)["tabs"]
+	want := Bytes(content, defaultOptions)
+
+	for i := range len(content) + 1 {
+		for j := i; j <= len(content); j++ {
+			parts := [][]byte{content[:i], content[i:j], content[j:]}
+			c := NewCounter(defaultOptions)
+			_, _ = c.Write(parts[0])
+			for _, part := range parts[1:] {
+				next := NewCounter(defaultOptions)
+				_, _ = next.Write(part)
+				c.Merge(next)
+			}
+
+			if got := c.Result(); got != want {
+				t.Fatalf("merge at %d and %d = %+v, want %+v", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestReaderAtMatchesSerial(t *testing.T) {
+	optionSets := map[string]Options{
+		"default":   defaultOptions,
+		"skip":      {Invalid: InvalidSkip},
+		"graphemes": {Invalid: InvalidCount, Graphemes: true},
+		"posix":     {Invalid: InvalidCount, Words: WordsPOSIX},
+		"uax29":     {Invalid: InvalidCount, Words: WordsUAX29},
+		"any eol":   {Invalid: InvalidCount, EOL: EOLAny},
+		"lines":     {Invalid: InvalidCount, LinesOnly: true},
+		"no width":  {Invalid: InvalidCount, NoWidth: true},
+	}
+
+	for name, content := range chunkCorpus(t) {
+		for optsName, opts := range optionSets {
+			t.Run(name+" "+optsName, func(t *testing.T) {
+				want, err := Reader(bytes.NewReader(content), opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for n := 2; n <= 16; n++ {
+					got, err := ReaderAt(bytes.NewReader(content), int64(len(content)), n, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got != want {
+						t.Fatalf("ReaderAt() with %d chunks = %+v, want %+v", n, got, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestChunkOffsets(t *testing.T) {
+	content := []byte("日本語日本語\nab\ncd")
+	tests := []struct {
+		name        string
+		lineAligned bool
+		want        []int64
+	}{
+		// targets 6 and 12 are on runes, 18 starts a rune too
+		{"runes", false, []int64{0, 6, 12, 18, 24}},
+		// every target before the newline moves to the same line
+		{"lines", true, []int64{0, 19, 24}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chunkOffsets(bytes.NewReader(content), int64(len(content)), 4, tt.lineAligned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chunkOffsets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package count
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// maxCodeLine is the longest part of a line that is classified, the rest of
+// a longer line does not change its class
+const maxCodeLine = BufferSize
+
+// Language is the comment and string syntax used to classify the lines of
+// a source file as code, comment or blank
+type Language struct {
+	Name          string
+	LineComments  []string    // markers of comments that end with the line
+	BlockComments [][2]string // start and end markers of comments
+	// string delimiters, strings may contain comment markers
+	Strings          []string
+	MultilineStrings []string // delimiters of strings that can span lines
+	RawStrings       []string // delimiters of strings without backslash escapes
+	// comments only start at the beginning of a word, as # in shell
+	// scripts where ${#var} is not a comment
+	WordComments bool
+	// a string starting a line with one of the multi-line delimiters is a
+	// docstring, which is a comment
+	DocStrings bool
+}
+
+var (
+	cStrings = []string{`"`, `'`}
+	cComment = [][2]string{{"/*", "*/"}}
+
+	// Text has no comments, every line that is not blank is code
+	Text = &Language{Name: "Text"}
+
+	Go = &Language{Name: "Go", LineComments: []string{"//"}, BlockComments: cComment,
+		Strings: []string{`"`, `'`, "`"}, MultilineStrings: []string{"`"}, RawStrings: []string{"`"}}
+	C          = &Language{Name: "C", LineComments: []string{"//"}, BlockComments: cComment, Strings: cStrings}
+	CPlusPlus  = &Language{Name: "C++", LineComments: []string{"//"}, BlockComments: cComment, Strings: cStrings}
+	CSharp     = &Language{Name: "C#", LineComments: []string{"//"}, BlockComments: cComment, Strings: cStrings}
+	Java       = &Language{Name: "Java", LineComments: []string{"//"}, BlockComments: cComment, Strings: []string{`"""`, `"`, `'`}, MultilineStrings: []string{`"""`}}
+	Kotlin     = &Language{Name: "Kotlin", LineComments: []string{"//"}, BlockComments: cComment, Strings: []string{`"""`, `"`, `'`}, MultilineStrings: []string{`"""`}}
+	Swift      = &Language{Name: "Swift", LineComments: []string{"//"}, BlockComments: cComment, Strings: []string{`"""`, `"`}, MultilineStrings: []string{`"""`}}
+	Rust       = &Language{Name: "Rust", LineComments: []string{"//"}, BlockComments: cComment, Strings: []string{`"`}, MultilineStrings: []string{`"`}}
+	JavaScript = &Language{Name: "JavaScript", LineComments: []string{"//"}, BlockComments: cComment, Strings: []string{`"`, `'`, "`"}, MultilineStrings: []string{"`"}}
+	TypeScript = &Language{Name: "TypeScript", LineComments: []string{"//"}, BlockComments: cComment, Strings: []string{`"`, `'`, "`"}, MultilineStrings: []string{"`"}}
+	Python     = &Language{Name: "Python", LineComments: []string{"#"},
+		Strings: []string{`"""`, `'''`, `"`, `'`}, MultilineStrings: []string{`"""`, `'''`}, DocStrings: true}
+	Shell = &Language{Name: "Shell", LineComments: []string{"#"}, Strings: []string{`"`, `'`},
+		MultilineStrings: []string{`"`, `'`}, RawStrings: []string{`'`}, WordComments: true}
+	YAML = &Language{Name: "YAML", LineComments: []string{"#"}, Strings: []string{`"`, `'`},
+		RawStrings: []string{`'`}, WordComments: true}
+	Markdown = &Language{Name: "Markdown", BlockComments: [][2]string{{"<!--", "-->"}}}
+)
+
+// extensions maps file extensions to their language
+var extensions = map[string]*Language{
+	".go": Go,
+	".c":  C, ".h": C,
+	".cc": CPlusPlus, ".cpp": CPlusPlus, ".cxx": CPlusPlus, ".hh": CPlusPlus, ".hpp": CPlusPlus, ".hxx": CPlusPlus,
+	".cs":   CSharp,
+	".java": Java,
+	".kt":   Kotlin, ".kts": Kotlin,
+	".swift": Swift,
+	".rs":    Rust,
+	".js":    JavaScript, ".mjs": JavaScript, ".cjs": JavaScript, ".jsx": JavaScript,
+	".ts": TypeScript, ".tsx": TypeScript,
+	".py": Python, ".pyi": Python,
+	".sh": Shell, ".bash": Shell, ".zsh": Shell, ".ksh": Shell,
+	".yml": YAML, ".yaml": YAML,
+	".md": Markdown, ".markdown": Markdown,
+}
+
+// LanguageOf returns the language of a file by its extension, or Text when
+// the extension is not known
+func LanguageOf(name string) *Language {
+	if lang, ok := extensions[strings.ToLower(filepath.Ext(name))]; ok {
+		return lang
+	}
+	return Text
+}
+
+// codeState is what a line of code inherits from the previous lines
+type codeState struct {
+	blockEnd string // end marker of the open block comment
+	quote    string // delimiter of the open multi-line string
+	doc      bool   // the open string is a docstring
+}
+
+func hasAnyPrefix(line []byte, prefixes []string) (string, bool) {
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(line, []byte(prefix)) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+func isCodeSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\v' || b == '\f'
+}
+
+// classify counts a line, without its line feed, in the code, comment or
+// blank lines of r. A line with code is code even if it has a comment.
+func (l *Language) classify(line []byte, state *codeState, r *Result) {
+	code, comment := false, false
+	// only a string at the start of a line can be a docstring
+	statementStart := true
+
+	for i := 0; i < len(line); {
+		switch {
+		case state.blockEnd != "":
+			comment = true
+			end := bytes.Index(line[i:], []byte(state.blockEnd))
+			if end < 0 {
+				i = len(line)
+				continue
+			}
+			i += end + len(state.blockEnd)
+			state.blockEnd = ""
+
+		case state.quote != "":
+			if state.doc {
+				comment = true
+			} else {
+				code = true
+			}
+			i = l.closeString(line, i, state)
+
+		case isCodeSpace(line[i]):
+			i++
+
+		default:
+			wordStart := i == 0 || isCodeSpace(line[i-1])
+			if _, ok := hasAnyPrefix(line[i:], l.LineComments); ok && (!l.WordComments || wordStart) {
+				comment = true
+				i = len(line)
+				continue
+			}
+			if marker, ok := l.blockComment(line[i:]); ok && (!l.WordComments || wordStart) {
+				comment = true
+				state.blockEnd = marker[1]
+				i += len(marker[0])
+				continue
+			}
+			if quote, ok := hasAnyPrefix(line[i:], l.Strings); ok {
+				_, multiline := hasAnyPrefix([]byte(quote), l.MultilineStrings)
+				state.quote = quote
+				state.doc = l.DocStrings && statementStart && multiline && len(quote) == 3
+				if state.doc {
+					comment = true
+				} else {
+					code = true
+				}
+				i = l.closeString(line, i+len(quote), state)
+				statementStart = false
+				continue
+			}
+			code = true
+			statementStart = false
+			i++
+		}
+	}
+
+	if state.quote != "" {
+		if _, ok := hasAnyPrefix([]byte(state.quote), l.MultilineStrings); !ok {
+			// an unterminated string ends with the line
+			state.quote = ""
+		}
+	}
+
+	switch {
+	case code:
+		r.Code++
+	case comment:
+		r.Comment++
+	default:
+		r.Blank++
+	}
+}
+
+func (l *Language) blockComment(line []byte) ([2]string, bool) {
+	for _, marker := range l.BlockComments {
+		if bytes.HasPrefix(line, []byte(marker[0])) {
+			return marker, true
+		}
+	}
+	return [2]string{}, false
+}
+
+// closeString skips the content of the open string from i and returns the
+// index after its closing delimiter, or the end of the line
+func (l *Language) closeString(line []byte, i int, state *codeState) int {
+	_, raw := hasAnyPrefix([]byte(state.quote), l.RawStrings)
+	for i < len(line) {
+		switch {
+		case line[i] == '\\' && !raw:
+			i += 2
+		case bytes.HasPrefix(line[i:], []byte(state.quote)):
+			i += len(state.quote)
+			state.quote = ""
+			state.doc = false
+			return i
+		default:
+			i++
+		}
+	}
+	return len(line)
+}
@@ -0,0 +1,109 @@
+package count
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLanguageOf(t *testing.T) {
+	tests := []struct {
+		name string
+		want *Language
+	}{
+		{"main.go", Go},
+		{"lib/x.H", C},
+		{"a.cpp", CPlusPlus},
+		{"app.tsx", TypeScript},
+		{"setup.py", Python},
+		{"run.sh", Shell},
+		{".github/ci.yml", YAML},
+		{"README.md", Markdown},
+		{"Makefile", Text},
+		{"notes.txt", Text},
+	}
+
+	for _, tt := range tests {
+		if got := LanguageOf(tt.name); got != tt.want {
+			t.Errorf("LanguageOf(%q) = %s, want %s", tt.name, got.Name, tt.want.Name)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name                 string
+		lang                 *Language
+		content              string
+		code, comment, blank int64
+	}{
+		{"empty", Go, "", 0, 0, 0},
+		{"blank lines", Go, "\n  \n\t\r\n", 0, 0, 3},
+		{"go", Go, "package main\n\n// main runs\nfunc main() {} // trailing\n", 2, 1, 1},
+		{"go block", Go, "/*\n  doc\n\n*/\nx := 1 /* a */\n/* a */ /* b */\n", 1, 4, 1},
+		{"go block then code", Go, "/* a\n*/ x := 1\n", 1, 1, 0},
+		{"go strings", Go, "s := \"// not a comment\"\nr := '\"' // quote\nt := \"\\\" /*\"\n", 3, 0, 0},
+		{"go raw string", Go, "s := `\n// inside\n/* inside\n`\n// comment\n", 4, 1, 0},
+		{"unterminated string", C, "s = \"abc\n// comment\n", 1, 1, 0},
+		{"c", C, "#include <stdio.h>\n/** doc\n * more\n */\nint main(void) { return 0; }\n", 2, 3, 0},
+		{"javascript template", JavaScript, "const s = `\n/* not a comment */\n`;\n", 3, 0, 0},
+		{"python", Python, "#!/usr/bin/env python\n# comment\nx = '#' # c\n\n", 1, 2, 1},
+		{"python docstring", Python, "def f():\n    \"\"\"Doc\n\n    # not code\n    \"\"\"\n    return \"\"\"a\n# b\"\"\"\n", 3, 3, 1},
+		{"python single line docstring", Python, "'''doc'''\nx = 1\n", 1, 1, 0},
+		{"shell", Shell, "#!/bin/sh\necho $# ${#x} # count\necho '#' \"a\n# b\"\n", 3, 1, 0},
+		{"yaml", YAML, "# config\nkey: value # c\nurl: http://x/#anchor\nq: \"# no\"\n", 3, 1, 0},
+		{"markdown", Markdown, "# Title\n\n<!-- hidden\n-->\ntext <!-- note -->\n", 2, 2, 1},
+		{"text", Text, "# not a comment\n\nx\n", 2, 0, 1},
+		{"last line", Go, "x := 1\n// end", 1, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Language: tt.lang}
+			got, err := Reader(iotest.OneByteReader(strings.NewReader(tt.content)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Code != tt.code || got.Comment != tt.comment || got.Blank != tt.blank {
+				t.Errorf("code, comment, blank = %d, %d, %d, want %d, %d, %d",
+					got.Code, got.Comment, got.Blank, tt.code, tt.comment, tt.blank)
+			}
+			if got != Bytes([]byte(tt.content), opts) {
+				t.Errorf("one byte reads differ from a single write")
+			}
+		})
+	}
+}
+
+func TestCodeResultKeepsState(t *testing.T) {
+	c := NewCounter(Options{Language: Go})
+	_, _ = c.Write([]byte("x := 1 /* open"))
+	if got := c.Result(); got.Code != 1 || got.Comment != 0 {
+		t.Errorf("code, comment = %d, %d, want 1, 0", got.Code, got.Comment)
+	}
+	_, _ = c.Write([]byte(" */\n// c\n"))
+	if got := c.Result(); got.Comment != 1 || got.Code != 1 {
+		t.Errorf("code, comment = %d, %d, want 1, 1", got.Code, got.Comment)
+	}
+}
+
+func TestCodeLongLine(t *testing.T) {
+	content := append(bytes.Repeat([]byte(" "), 2*maxCodeLine), "x\n// c\n"...)
+	got := Bytes(content, Options{Language: Go})
+	if got.Code != 0 || got.Comment != 1 || got.Blank != 1 {
+		t.Errorf("code, comment, blank = %d, %d, %d, want 0, 1, 1", got.Code, got.Comment, got.Blank)
+	}
+}
+
+func TestCodeReaderAt(t *testing.T) {
+	content := []byte(strings.Repeat("/*\n\n*/\nx := `\n// y\n`\n", 100))
+	opts := Options{Language: Go}
+	got, err := ReaderAt(bytes.NewReader(content), int64(len(content)), 8, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Bytes(content, opts); got != want {
+		t.Errorf("ReaderAt = %+v, want %+v", got, want)
+	}
+}
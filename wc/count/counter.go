@@ -0,0 +1,665 @@
+// Package count counts the bytes, lines, words, characters and maximum line
+// display width of a stream in a single pass, like the Unix wc tool.
+//
+// A Counter is an io.Writer, so it can be fed by io.Copy or combined with
+// other writers. Counters of consecutive parts of an input can be merged,
+// which allows counting a large file concurrently.
+package count
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BufferSize is the fixed amount of memory used to read an input
+const BufferSize = 64 * 1024
+
+// Result holds the counts of an input
+type Result struct {
+	Bytes int64
+	Lines int64
+	Words int64
+	Chars int64
+	// display width of the longest line and its line number, starting at 1
+	MaxLineLength int64
+	LongestLine   int64
+	// line terminators: lone line feeds, CRLF and lone carriage returns
+	LF   int64
+	CRLF int64
+	CR   int64
+	// 1 when the input is not empty and does not end with a line terminator
+	Unterminated int64
+	// lines of source code by their content, when a Language is set
+	Code    int64
+	Comment int64
+	Blank   int64
+	// matches of the Pattern and lines with at least one
+	Matches       int64
+	MatchingLines int64
+}
+
+// Add accumulates the counts of other into r as if the input of other
+// followed the input of r, the longest line is numbered accordingly
+func (r *Result) Add(other Result) {
+	r.Bytes += other.Bytes
+	r.Words += other.Words
+	r.Chars += other.Chars
+	r.LF += other.LF
+	r.CRLF += other.CRLF
+	r.CR += other.CR
+	r.Unterminated += other.Unterminated
+	r.Code += other.Code
+	r.Comment += other.Comment
+	r.Blank += other.Blank
+	r.Matches += other.Matches
+	r.MatchingLines += other.MatchingLines
+	if other.MaxLineLength > r.MaxLineLength {
+		r.MaxLineLength = other.MaxLineLength
+		r.LongestLine = r.Lines + other.LongestLine
+	}
+	r.Lines += other.Lines
+}
+
+// InvalidPolicy controls how bytes that are not valid UTF-8 are counted as
+// characters, the zero value counts them. It implements flag.Value.
+type InvalidPolicy string
+
+const (
+	InvalidCount InvalidPolicy = "count" // every invalid byte is one character
+	InvalidSkip  InvalidPolicy = "skip"  // invalid bytes are not characters
+)
+
+func (p *InvalidPolicy) String() string {
+	return string(*p)
+}
+
+func (p *InvalidPolicy) Set(value string) error {
+	switch policy := InvalidPolicy(value); policy {
+	case InvalidCount, InvalidSkip:
+		*p = policy
+		return nil
+	default:
+		return fmt.Errorf("invalid policy %q, expected count or skip", value)
+	}
+}
+
+// Options configures how a Counter interprets its input
+type Options struct {
+	Invalid   InvalidPolicy
+	Graphemes bool // count grapheme clusters instead of code points
+	Words     WordRule
+	EOL       EOL // the line terminators counted as lines
+	// count the occurrences of every word, when not nil
+	Frequencies *FrequencyOptions
+	// classify the lines ended by line feeds as code, comment or blank,
+	// when not nil
+	Language *Language
+	// collect the distribution of the line lengths
+	Stats bool
+	// the character encoding of the input, UTF-8 when empty
+	Encoding Encoding
+	// count the matches in the lines ended by line feeds, when not nil
+	Pattern *regexp.Regexp
+	// only count the bytes and the line feeds, every other count is zero.
+	// The runes are still decoded when EOL, Encoding or an option above
+	// needs them.
+	LinesOnly bool
+	// do not measure the display width of the lines, MaxLineLength and
+	// LongestLine are zero
+	NoWidth bool
+}
+
+// LineAligned reports whether counters can only be merged at the start of a
+// line. Grapheme clusters and word segments depend on more context than the
+// previous rune, so they are not tracked across other boundaries, and a
+// pattern is matched against whole lines.
+func (o Options) LineAligned() bool {
+	return o.Graphemes || o.Words == WordsUAX29 || o.Pattern != nil
+}
+
+// linesOnly reports whether the lines can be counted without decoding the
+// runes: the bytes of line feeds in UTF-8 and Latin-1 are never part of
+// another character.
+func (o Options) linesOnly() bool {
+	return o.LinesOnly && (o.EOL == "" || o.EOL == EOLLF) && o.Encoding.splittable() &&
+		o.Frequencies == nil && o.Language == nil && !o.Stats && o.Pattern == nil
+}
+
+// Splittable reports whether an input can be counted in parts and merged.
+// Block comments and strings span lines, so a line cannot be classified
+// without the lines before it, and some encodings need the start of the
+// input to be decoded.
+func (o Options) Splittable() bool {
+	return o.Language == nil && o.Encoding.splittable()
+}
+
+// Counter accumulates counts from a stream of bytes in a single pass.
+// Runes and words may be split across calls to Write, so the counter keeps
+// the bytes of an incomplete rune and whether the last rune was inside a word.
+//
+// A Counter can also count a part of a larger input and be merged with the
+// counter of the following part, so it remembers how the part starts: whether
+// it starts inside a word and the width of its first line, which continues
+// the last line of the previous part.
+type Counter struct {
+	counts   Result
+	opts     Options
+	fast     bool // only the line feeds are counted
+	inWord   bool
+	column   int64 // display width of the current line so far
+	grapheme graphemeBreaker
+	segments wordSegmenter
+	pending  int               // number of buffered bytes of an incomplete rune
+	carry    [utf8.UTFMax]byte // bytes of an incomplete rune
+
+	// the encoding of the input, once its byte order mark was read
+	encoding Encoding
+	sniffing bool    // looking for a byte order mark at the start of the input
+	bom      [3]byte // first bytes of the input, while sniffing
+	bomLen   int
+
+	started     bool        // at least one rune was counted
+	leadingWord bool        // the first rune is not a space
+	lineEnded   bool        // a line break or carriage return was counted
+	leadingLF   bool        // the first rune is a line feed, it may end a CRLF
+	pendingCR   bool        // the last rune is a carriage return, it may start a CRLF
+	terminated  bool        // the last rune is a line terminator
+	head        lineSegment // the first line, until lineEnded
+	// longest line after the first one
+	restMaxLineLength int64
+	restLongestLine   int64
+
+	// occurrences of the words, when counted
+	frequencies *Frequencies
+	word        []byte // bytes of the current word
+	// first word when the input starts inside a word, it may continue the
+	// last word of the previous input
+	headWord      []byte
+	headWordEnded bool
+
+	// the current line and what it inherits, when classifying code
+	line []byte
+	code codeState
+
+	// the current line, up to maxMatchLine bytes, when matching a pattern
+	matchLine []byte
+
+	// line lengths, when collected
+	stats     *LineStats
+	lineBytes int64 // bytes of the current line so far
+	// length of the first line, once ended, it may continue the last line
+	// of the previous input
+	headBytes int64
+	lineFed   bool
+}
+
+// NewCounter returns a Counter for an input starting at the first line
+func NewCounter(opts Options) *Counter {
+	c := &Counter{opts: opts, encoding: opts.Encoding, fast: opts.linesOnly()}
+	switch opts.Encoding {
+	case EncodingAuto, EncodingUTF16LE, EncodingUTF16BE:
+		c.sniffing = true
+	}
+	if opts.Frequencies != nil {
+		c.frequencies = NewFrequencies()
+	}
+	if opts.Stats {
+		c.stats = NewLineStats()
+	}
+	return c
+}
+
+// Write feeds p into the counter, it never returns an error
+func (c *Counter) Write(p []byte) (int, error) {
+	c.counts.Bytes += int64(len(p))
+	if c.fast {
+		lines := int64(bytes.Count(p, []byte{'\n'}))
+		c.counts.LF += lines
+		c.counts.Lines += lines
+		return len(p), nil
+	}
+	c.decode(p)
+	return len(p), nil
+}
+
+// Result returns the counts of everything written so far. A rune left
+// incomplete counts as invalid bytes and the last line may not end with a
+// newline. The counter can still be written to afterwards.
+func (c *Counter) Result() Result {
+	end := c.end()
+
+	result := end.counts
+	if end.column > result.MaxLineLength {
+		result.MaxLineLength = end.column
+		result.LongestLine = end.counts.Lines + 1
+	}
+	if end.pendingCR {
+		result.CR++
+	}
+	if end.started && !end.terminated {
+		result.Unterminated = 1
+	}
+	// the longest line is still numbered by line feeds
+	result.Lines = end.opts.EOL.lines(result)
+	if end.opts.Language != nil && len(end.line) > 0 {
+		// the state is a copy, the last line may still continue
+		end.opts.Language.classify(end.line, &end.code, &result)
+	}
+	if end.opts.Pattern != nil && len(end.matchLine) > 0 {
+		countMatches(end.opts.Pattern, end.matchLine, &result)
+	}
+	return result
+}
+
+// Frequencies returns the occurrences of the words written so far, or nil
+// when the options do not count them
+func (c *Counter) Frequencies() *Frequencies {
+	if c.frequencies == nil {
+		return nil
+	}
+	end := c.end()
+
+	f := NewFrequencies()
+	f.Add(c.frequencies)
+	f.Add(end.frequencies)
+	if end.headWordEnded {
+		f.addWord(c.opts.Frequencies.word(end.headWord), 1)
+	}
+	if end.inWord {
+		f.addWord(c.opts.Frequencies.word(end.word), 1)
+	}
+	return f
+}
+
+// LineStats returns the distribution of the lengths of the lines written so
+// far, or nil when the options do not collect it
+func (c *Counter) LineStats() *LineStats {
+	if c.stats == nil {
+		return nil
+	}
+	end := c.end()
+
+	s := end.stats
+	if end.lineFed {
+		s.add(end.headBytes)
+	}
+	if end.lineBytes > 0 {
+		s.add(end.lineBytes)
+	}
+	return s
+}
+
+// end returns a copy of c at the end of its input, with a rune left
+// incomplete flushed. The copy does not write to the state it shares with c:
+// it has its own line statistics and current word, and the words it ends are
+// counted in frequencies of its own.
+func (c *Counter) end() *Counter {
+	end := *c
+	end.word = bytes.Clone(c.word)
+	// a flush does not end lines, so appending to them never overwrites the
+	// bytes of c
+	end.line = slices.Clip(c.line)
+	end.matchLine = slices.Clip(c.matchLine)
+	if c.frequencies != nil {
+		end.frequencies = NewFrequencies()
+	}
+	if c.stats != nil {
+		end.stats = c.stats.clone()
+	}
+	end.flush()
+	return &end
+}
+
+// flush counts the bytes of a rune left incomplete at the end of the input
+// as invalid runes
+func (c *Counter) flush() {
+	c.flushEncoding()
+}
+
+// endLine records the width of the current line and starts a new one
+func (c *Counter) endLine() {
+	c.lineWidth(c.column, c.counts.Lines+1, c.lineEnded)
+	c.lineEnded = true
+	c.column = 0
+}
+
+// lineWidth records the width of a complete line. The width of the first
+// line is only known once merged with the previous counter, so the longest
+// of the rest of the lines is kept apart.
+func (c *Counter) lineWidth(width, line int64, rest bool) {
+	if width > c.counts.MaxLineLength {
+		c.counts.MaxLineLength = width
+		c.counts.LongestLine = line
+	}
+	if rest && width > c.restMaxLineLength {
+		c.restMaxLineLength = width
+		c.restLongestLine = line
+	}
+}
+
+// Merge adds the counts of next, which counted the input that immediately
+// follows the input of c, and continues counting after it. The inputs must
+// be split at the start of a rune, and at the start of a line when the
+// options are LineAligned. Options that are not Splittable cannot be merged
+// as next would classify its lines without the state of c. next must not be
+// used afterwards.
+func (c *Counter) Merge(next *Counter) {
+	c.flush()
+	next.flush()
+
+	// the last line of c continues with the first line of next
+	headEnd := next.head.end(c.column)
+	if next.lineEnded {
+		c.lineWidth(headEnd, c.counts.Lines+1, c.lineEnded)
+		c.lineWidth(next.restMaxLineLength, c.counts.Lines+next.restLongestLine, true)
+		c.column = next.column
+	} else {
+		c.column = headEnd
+	}
+	if !c.lineEnded {
+		c.head = c.head.then(next.head)
+	}
+	c.lineEnded = c.lineEnded || next.lineEnded
+
+	if c.stats != nil {
+		c.mergeStats(next)
+	}
+	// merged at a line start, the current line of c is empty unless next
+	// has no line feed
+	if len(c.matchLine) < maxMatchLine {
+		c.matchLine = append(c.matchLine, next.matchLine...)
+		c.matchLine = c.matchLine[:min(len(c.matchLine), maxMatchLine)]
+	}
+
+	// a carriage return at the end of c ends with the first rune of next
+	if c.pendingCR && next.started {
+		if next.leadingLF {
+			c.counts.CRLF++
+			next.counts.LF--
+		} else {
+			c.counts.CR++
+		}
+		c.pendingCR = false
+	}
+	c.counts.LF += next.counts.LF
+	c.counts.CRLF += next.counts.CRLF
+	c.counts.CR += next.counts.CR
+	if next.started {
+		c.pendingCR = next.pendingCR
+		c.terminated = next.terminated
+	}
+
+	if !c.started {
+		c.started = next.started
+		c.leadingWord = next.leadingWord
+		c.leadingLF = next.leadingLF
+	}
+	if c.frequencies != nil {
+		c.mergeWords(next)
+	}
+
+	// a word split between the two inputs is counted by both
+	c.counts.Words += next.counts.Words
+	if c.inWord && next.leadingWord {
+		c.counts.Words--
+	}
+	if next.started {
+		c.inWord = next.inWord
+		c.grapheme = next.grapheme
+		c.segments = next.segments
+	}
+
+	c.counts.Bytes += next.counts.Bytes
+	c.counts.Lines += next.counts.Lines
+	c.counts.Chars += next.counts.Chars
+	c.counts.Code += next.counts.Code
+	c.counts.Comment += next.counts.Comment
+	c.counts.Blank += next.counts.Blank
+	c.counts.Matches += next.counts.Matches
+	c.counts.MatchingLines += next.counts.MatchingLines
+}
+
+// mergeWords adds the word occurrences of next, joining the current word of
+// c with the first word of next when the split is inside a word
+func (c *Counter) mergeWords(next *Counter) {
+	c.frequencies.Add(next.frequencies)
+	if !next.started {
+		return
+	}
+
+	if next.leadingWord {
+		first := next.word
+		if next.headWordEnded {
+			first = next.headWord
+		}
+		c.word = append(c.word, first...)
+		if !next.headWordEnded {
+			// next is a single word that has not ended yet
+			return
+		}
+		c.endWord()
+	} else if c.inWord {
+		c.endWord()
+	}
+	c.word = append(c.word, next.word...)
+}
+
+// mergeStats adds the line lengths of next, the last line of c continues
+// with the first line of next
+func (c *Counter) mergeStats(next *Counter) {
+	c.stats.Add(next.stats)
+	if !next.lineFed {
+		c.lineBytes += next.lineBytes
+		return
+	}
+
+	length := c.lineBytes + next.headBytes
+	if c.pendingCR && next.leadingLF {
+		// the carriage return ending c is the start of a CRLF
+		length--
+	}
+	c.endLineBytes(length)
+	c.lineBytes = next.lineBytes
+}
+
+// endLineBytes records the length of a line ended by a line feed, except
+// the first line of an input, which is only known once merged
+func (c *Counter) endLineBytes(length int64) {
+	if !c.lineFed {
+		c.headBytes = length
+		c.lineFed = true
+		return
+	}
+	c.stats.add(length)
+}
+
+// endWord counts the occurrence of the current word, except the first word
+// of an input that starts inside a word, which is only known once merged
+func (c *Counter) endWord() {
+	if c.leadingWord && !c.headWordEnded {
+		c.headWord = bytes.Clone(c.word)
+		c.headWordEnded = true
+	} else {
+		c.frequencies.addWord(c.opts.Frequencies.word(c.word), 1)
+	}
+	c.word = c.word[:0]
+}
+
+// rune counts a decoded rune of size bytes of the input, or size invalid
+// bytes counted as one utf8.RuneError
+func (c *Counter) rune(r rune, size int, invalid bool) {
+	switch {
+	case invalid && c.opts.Invalid == InvalidSkip:
+	case c.opts.Graphemes:
+		if invalid {
+			// an invalid byte is a cluster of its own
+			c.grapheme.reset()
+			c.counts.Chars++
+		} else if c.grapheme.next(r) {
+			c.counts.Chars++
+		}
+	default:
+		c.counts.Chars++
+	}
+
+	switch {
+	case r == '\n' && c.pendingCR:
+		c.counts.CRLF++
+	case r == '\n':
+		c.counts.LF++
+	case c.pendingCR:
+		c.counts.CR++
+	}
+	if !c.started {
+		c.leadingLF = r == '\n'
+	}
+	crlf := r == '\n' && c.pendingCR
+	c.pendingCR = r == '\r'
+	c.terminated = r == '\n' || r == '\r'
+	if c.opts.Language != nil {
+		c.codeRune(r)
+	}
+	if c.stats != nil {
+		c.statsRune(r, size, crlf)
+	}
+	if c.opts.Pattern != nil {
+		c.matchRune(r, crlf)
+	}
+
+	switch {
+	case r == '\n':
+		c.endLine()
+		c.counts.Lines++
+	case r == '\r', r == '\f':
+		// carriage returns and form feeds go back to the start of the line
+		c.endLine()
+	case c.opts.NoWidth:
+	case r == '\t':
+		c.column = nextTabStop(c.column)
+		if !c.lineEnded {
+			c.head.tab()
+		}
+	default:
+		width := int64(1) // an invalid byte is shown as a replacement character
+		if !invalid {
+			width = runeWidth(r)
+		}
+		c.column += width
+		if !c.lineEnded {
+			c.head.add(width)
+		}
+	}
+
+	if c.opts.Words == WordsUAX29 {
+		c.started = true
+		c.segmentRune(r)
+		return
+	}
+
+	space := c.opts.Words.isSpace(r)
+	if !c.started {
+		c.started = true
+		c.leadingWord = !space
+	}
+	if space {
+		if c.inWord && c.frequencies != nil {
+			c.endWord()
+		}
+		c.inWord = false
+		return
+	}
+	if !c.inWord {
+		c.inWord = true
+		c.counts.Words++
+	}
+	if c.frequencies != nil {
+		c.word = utf8.AppendRune(c.word, r)
+	}
+}
+
+// statsRune measures the current line, without its line terminator
+func (c *Counter) statsRune(r rune, size int, crlf bool) {
+	if r != '\n' {
+		c.lineBytes += int64(size)
+		return
+	}
+	length := c.lineBytes
+	if crlf {
+		length--
+	}
+	c.endLineBytes(length)
+	c.lineBytes = 0
+}
+
+// codeRune collects the current line and classifies it once it ends, only
+// the first maxCodeLine bytes of a line are kept
+func (c *Counter) codeRune(r rune) {
+	if r == '\n' {
+		c.opts.Language.classify(c.line, &c.code, &c.counts)
+		c.line = c.line[:0]
+		return
+	}
+	if len(c.line) < maxCodeLine {
+		c.line = utf8.AppendRune(c.line, r)
+	}
+}
+
+// segmentRune counts the UAX #29 segments that contain a letter or a digit.
+// Counters are merged at line starts, where a segment always starts, so no
+// word is leading.
+func (c *Counter) segmentRune(r rune) {
+	if c.segments.next(r) {
+		if c.inWord && c.frequencies != nil {
+			c.endWord()
+		}
+		c.inWord = false
+		c.word = c.word[:0]
+	}
+	if !c.inWord && isWordRune(r) {
+		c.inWord = true
+		c.counts.Words++
+	}
+	if c.frequencies != nil && (c.inWord || !unicode.IsSpace(r)) {
+		// the segment may still become a word, such as "_a"
+		c.word = utf8.AppendRune(c.word, r)
+	}
+}
+
+// ReadFrom feeds r to the counter using a fixed size buffer until io.EOF, it
+// implements io.ReaderFrom
+func (c *Counter) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	buf := make([]byte, BufferSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			_, _ = c.Write(buf[:n])
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Reader counts r in a single pass using a fixed size buffer
+func Reader(r io.Reader, opts Options) (Result, error) {
+	c := NewCounter(opts)
+	_, err := c.ReadFrom(r)
+	return c.Result(), err
+}
+
+// Bytes counts an in-memory buffer
+func Bytes(content []byte, opts Options) Result {
+	c := NewCounter(opts)
+	_, _ = c.Write(content)
+	return c.Result()
+}
@@ -0,0 +1,183 @@
+package count
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+var defaultOptions = Options{Invalid: InvalidCount}
+
+func TestReaderBufferBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"ascii", "hello world\nthis is a test\n"},
+		{"unicode", "café naïve\n日本語 テキスト\n"},
+		{"unicode spaces", "hello\u00a0world\u2003again\n"},
+		{"invalid utf8", "abc \xe2\x82 def\xff\n"},
+		{"truncated rune at end", "hello \xe2\x82"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// one byte at a time splits every word and rune across reads
+			got, err := Reader(iotest.OneByteReader(strings.NewReader(tt.content)), defaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := Bytes([]byte(tt.content), defaultOptions); got != want {
+				t.Errorf("Reader() = %+v, want %+v", got, want)
+			}
+
+			if got.Bytes != int64(len(tt.content)) {
+				t.Errorf("Expected %d bytes, got %d", len(tt.content), got.Bytes)
+			}
+			if want := strings.Count(tt.content, "\n"); got.Lines != int64(want) {
+				t.Errorf("Expected %d lines, got %d", want, got.Lines)
+			}
+			if want := len(strings.Fields(tt.content)); got.Words != int64(want) {
+				t.Errorf("Expected %d words, got %d", want, got.Words)
+			}
+			if want := utf8.RuneCountInString(tt.content); got.Chars != int64(want) {
+				t.Errorf("Expected %d characters, got %d", want, got.Chars)
+			}
+		})
+	}
+}
+
+func TestReaderLargerThanBuffer(t *testing.T) {
+	content := strings.Repeat("hello wörld\n", 3*BufferSize/12+7)
+
+	got, err := Reader(strings.NewReader(content), defaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Bytes([]byte(content), defaultOptions)
+	if got != want {
+		t.Errorf("Reader() = %+v, want %+v", got, want)
+	}
+	if got.Words != int64(len(strings.Fields(content))) {
+		t.Errorf("Expected %d words, got %d", len(strings.Fields(content)), got.Words)
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	_, err := Reader(iotest.ErrReader(os.ErrClosed), defaultOptions)
+	if err == nil {
+		t.Error("Expected an error from a failing reader")
+	}
+}
+
+func TestCountCharacterModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		opts     Options
+		expected int64
+	}{
+		{"accented", "café", defaultOptions, 4},
+		{"combining accent", "cafe\u0301", defaultOptions, 5},
+		{"invalid bytes counted", "ab\xff\xfecd", defaultOptions, 6},
+		{"invalid bytes skipped", "ab\xff\xfecd", Options{Invalid: InvalidSkip}, 4},
+		{"truncated rune skipped", "ab\xe2\x82", Options{Invalid: InvalidSkip}, 2},
+		{"replacement character is valid", "a\uFFFDb", Options{Invalid: InvalidSkip}, 3},
+		{"graphemes combining accent", "cafe\u0301", Options{Graphemes: true}, 4},
+		{"graphemes crlf", "a\r\nb", Options{Graphemes: true}, 3},
+		{"graphemes skin tone", "\U0001F44B\U0001F3FD!", Options{Graphemes: true}, 2},
+		{"graphemes zwj family", "\U0001F468\u200D\U0001F469\u200D\U0001F467", Options{Graphemes: true}, 1},
+		{"graphemes flags", "\U0001F1EB\U0001F1F7\U0001F1EF\U0001F1F5\U0001F1FA", Options{Graphemes: true}, 3},
+		{"graphemes hangul jamo", "\u1100\u1161\u11A8", Options{Graphemes: true}, 1},
+		{"graphemes invalid bytes", "e\xff\u0301", Options{Graphemes: true}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Reader(iotest.OneByteReader(strings.NewReader(tt.content)), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Chars != tt.expected {
+				t.Errorf("chars = %d, want %d", got.Chars, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCountMaxLineLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		width       int64
+		longestLine int64
+	}{
+		{"empty", "", 0, 0},
+		{"ascii", "hello\nhello world\nhi\n", 11, 2},
+		{"first of equal lines", "abc\nabc\n", 3, 1},
+		{"no final newline", "a\nlongest", 7, 2},
+		{"tab stops", "a\tb\n", 9, 1},
+		{"tab at stop", "12345678\tx\n", 17, 1},
+		{"carriage return", "hello world\rhi\n", 11, 1},
+		{"wide characters", "日本語\nabcde\n", 6, 1},
+		{"combining accent", "cafe\u0301\n", 4, 1},
+		{"emoji", "\U0001F600\U0001F600 ok\n", 7, 1},
+		{"zero width", "a\u200Bb\n", 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Reader(iotest.OneByteReader(strings.NewReader(tt.content)), defaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.MaxLineLength != tt.width || got.LongestLine != tt.longestLine {
+				t.Errorf("max line length = %d at line %d, want %d at line %d",
+					got.MaxLineLength, got.LongestLine, tt.width, tt.longestLine)
+			}
+		})
+	}
+}
+
+func TestCountSkippedCounts(t *testing.T) {
+	content := "héllo\twörld\r\n日本語\nno final newline"
+	all := Bytes([]byte(content), defaultOptions)
+	noWidth := all
+	noWidth.MaxLineLength, noWidth.LongestLine = 0, 0
+
+	tests := []struct {
+		name string
+		opts Options
+		want Result
+	}{
+		// the line feed of a CRLF is a line feed
+		{"lines only", Options{LinesOnly: true}, Result{Bytes: all.Bytes, Lines: 2, LF: 2}},
+		{"lines only of other terminators", Options{LinesOnly: true, EOL: EOLAny}, Bytes([]byte(content), Options{EOL: EOLAny})},
+		{"lines only of UTF-16", Options{LinesOnly: true, Encoding: EncodingUTF16LE}, Bytes([]byte(content), Options{Encoding: EncodingUTF16LE})},
+		{"no width", Options{NoWidth: true}, noWidth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Reader(iotest.OneByteReader(strings.NewReader(content)), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Reader() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultAddLongestLine(t *testing.T) {
+	total := Bytes([]byte("short\nlines\n"), defaultOptions)
+	total.Add(Bytes([]byte("a\nthe longest line\n"), defaultOptions))
+
+	want := Bytes([]byte("short\nlines\na\nthe longest line\n"), defaultOptions)
+	if total != want {
+		t.Errorf("Add() = %+v, want %+v", total, want)
+	}
+}
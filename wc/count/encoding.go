@@ -0,0 +1,225 @@
+package count
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of the input, the zero value is UTF-8.
+// Bytes are always counted as they are read, the other counts are made on
+// the decoded text. It implements flag.Value.
+type Encoding string
+
+const (
+	EncodingAuto    Encoding = "auto"     // detected by the byte order mark, UTF-8 without one
+	EncodingUTF8    Encoding = "utf-8"    // a byte order mark is a character, like GNU wc
+	EncodingUTF16LE Encoding = "utf-16le" // little endian UTF-16, after an optional byte order mark
+	EncodingUTF16BE Encoding = "utf-16be" // big endian UTF-16, after an optional byte order mark
+	EncodingLatin1  Encoding = "latin1"   // ISO 8859-1, every byte is a character
+)
+
+func (e *Encoding) String() string {
+	return string(*e)
+}
+
+func (e *Encoding) Set(value string) error {
+	switch encoding := Encoding(value); encoding {
+	case EncodingAuto, EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE, EncodingLatin1:
+		*e = encoding
+		return nil
+	default:
+		return fmt.Errorf("invalid encoding %q, expected auto, utf-8, utf-16le, utf-16be or latin1", value)
+	}
+}
+
+// splittable reports whether an input in this encoding can be split at any
+// UTF-8 rune start. UTF-16 needs the parity of the offset and the detected
+// encoding is only known at the start.
+func (e Encoding) splittable() bool {
+	return e == "" || e == EncodingUTF8 || e == EncodingLatin1
+}
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// detectEncoding returns the encoding of an input starting with head and
+// the length of its byte order mark. It reports false when head is too short
+// to tell and more of the input follows.
+func detectEncoding(head []byte, final bool) (Encoding, int, bool) {
+	for _, bom := range []struct {
+		bytes    []byte
+		encoding Encoding
+	}{{bomUTF8, EncodingUTF8}, {bomUTF16LE, EncodingUTF16LE}, {bomUTF16BE, EncodingUTF16BE}} {
+		if bytes.HasPrefix(head, bom.bytes) {
+			return bom.encoding, len(bom.bytes), true
+		}
+		if !final && bytes.HasPrefix(bom.bytes, head) {
+			return "", 0, false
+		}
+	}
+	return EncodingUTF8, 0, true
+}
+
+// decode feeds p, raw bytes of the input, to the counter in its encoding
+func (c *Counter) decode(p []byte) {
+	if c.sniffing {
+		n := copy(c.bom[c.bomLen:], p)
+		c.bomLen += n
+		if !c.sniff(false) {
+			return
+		}
+		p = p[n:]
+	}
+
+	switch c.encoding {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		c.decodeUTF16(p)
+	case EncodingLatin1:
+		for _, b := range p {
+			c.rune(rune(b), 1, false)
+		}
+	default:
+		c.decodeUTF8(p)
+	}
+}
+
+// sniff looks for a byte order mark in the first bytes of the input, once
+// known the bytes after it are decoded. It reports false while the input is
+// too short to tell.
+func (c *Counter) sniff(final bool) bool {
+	encoding, bom, known := detectEncoding(c.bom[:c.bomLen], final)
+	if !known {
+		return false
+	}
+	if c.encoding == EncodingAuto {
+		c.encoding = encoding
+	} else if encoding != c.encoding {
+		// an explicit encoding only skips its own byte order mark
+		bom = 0
+	}
+	c.sniffing = false
+	c.decode(c.bom[bom:c.bomLen])
+	return true
+}
+
+// flushEncoding counts the bytes left at the end of the input, which do not
+// form a complete character, as invalid characters
+func (c *Counter) flushEncoding() {
+	if c.sniffing {
+		c.sniff(true)
+	}
+
+	switch c.encoding {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		if c.pending >= 2 {
+			c.rune(utf8.RuneError, 2, true) // an unpaired high surrogate
+		}
+		if c.pending%2 == 1 {
+			c.rune(utf8.RuneError, 1, true)
+		}
+	default:
+		for range c.pending {
+			c.rune(utf8.RuneError, 1, true)
+		}
+	}
+	c.pending = 0
+}
+
+// decodeUTF8 feeds UTF-8 bytes to the counter, keeping the bytes of a rune
+// split across calls to Write
+func (c *Counter) decodeUTF8(p []byte) {
+	if c.pending > 0 {
+		// complete the rune started in the previous buffer
+		copied := copy(c.carry[c.pending:], p)
+		buf := c.carry[:c.pending+copied]
+		if !utf8.FullRune(buf) {
+			c.pending = len(buf)
+			return
+		}
+		i := 0
+		for i < c.pending {
+			r, size := utf8.DecodeRune(buf[i:])
+			c.utf8Rune(r, size)
+			i += size
+		}
+		p = p[i-c.pending:]
+		c.pending = 0
+	}
+
+	for len(p) > 0 {
+		if p[0] < utf8.RuneSelf {
+			c.rune(rune(p[0]), 1, false)
+			p = p[1:]
+			continue
+		}
+		if !utf8.FullRune(p) {
+			c.pending = copy(c.carry[:], p)
+			break
+		}
+		r, size := utf8.DecodeRune(p)
+		c.utf8Rune(r, size)
+		p = p[size:]
+	}
+}
+
+// utf8Rune counts a decoded UTF-8 rune, a one byte utf8.RuneError is an
+// invalid byte
+func (c *Counter) utf8Rune(r rune, size int) {
+	c.rune(r, size, r == utf8.RuneError && size == 1)
+}
+
+// decodeUTF16 feeds UTF-16 bytes to the counter, keeping the odd byte of a
+// code unit and the high surrogate of a pair split across calls to Write
+func (c *Counter) decodeUTF16(p []byte) {
+	for _, b := range p {
+		c.carry[c.pending] = b
+		c.pending++
+		if c.pending%2 == 1 {
+			continue
+		}
+
+		unit := c.unitAt(c.pending - 2)
+		if c.pending == 4 {
+			high := c.unitAt(0)
+			c.pending = 0
+			if unit >= 0xdc00 && unit < 0xe000 {
+				c.rune(utf16.DecodeRune(rune(high), rune(unit)), 4, false)
+				continue
+			}
+			c.rune(utf8.RuneError, 2, true) // an unpaired high surrogate
+		}
+		c.pending = 0
+		c.unit(unit)
+	}
+}
+
+// unitAt returns the UTF-16 code unit buffered at i
+func (c *Counter) unitAt(i int) uint16 {
+	if c.encoding == EncodingUTF16BE {
+		return uint16(c.carry[i])<<8 | uint16(c.carry[i+1])
+	}
+	return uint16(c.carry[i+1])<<8 | uint16(c.carry[i])
+}
+
+// unit counts a code unit that is not the end of a surrogate pair, a high
+// surrogate is kept until the next unit
+func (c *Counter) unit(unit uint16) {
+	switch {
+	case unit >= 0xd800 && unit < 0xdc00:
+		if c.encoding == EncodingUTF16BE {
+			c.carry[0], c.carry[1] = byte(unit>>8), byte(unit)
+		} else {
+			c.carry[0], c.carry[1] = byte(unit), byte(unit>>8)
+		}
+		c.pending = 2
+	case unit >= 0xdc00 && unit < 0xe000:
+		c.rune(utf8.RuneError, 2, true) // an unpaired low surrogate
+	default:
+		c.rune(rune(unit), 2, false)
+	}
+}
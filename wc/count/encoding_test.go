@@ -0,0 +1,130 @@
+package count
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+func utf16Bytes(s string, bigEndian, bom bool) []byte {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	var buf []byte
+	for _, unit := range units {
+		if bigEndian {
+			buf = append(buf, byte(unit>>8), byte(unit))
+		} else {
+			buf = append(buf, byte(unit), byte(unit>>8))
+		}
+	}
+	return buf
+}
+
+func TestEncodings(t *testing.T) {
+	text := "name,city\r\nJosé,Zürich 🎉\r\n"
+	want := Bytes([]byte(text), Options{})
+
+	tests := []struct {
+		name     string
+		content  []byte
+		encoding Encoding
+	}{
+		{"utf-16le with bom", utf16Bytes(text, false, true), EncodingAuto},
+		{"utf-16be with bom", utf16Bytes(text, true, true), EncodingAuto},
+		{"utf-8 with bom", append(bytes.Clone(bomUTF8), text...), EncodingAuto},
+		{"utf-8 without bom", []byte(text), EncodingAuto},
+		{"explicit utf-16le", utf16Bytes(text, false, false), EncodingUTF16LE},
+		{"explicit utf-16le with bom", utf16Bytes(text, false, true), EncodingUTF16LE},
+		{"explicit utf-16be", utf16Bytes(text, true, false), EncodingUTF16BE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Encoding: tt.encoding}
+			got, err := Reader(iotest.OneByteReader(bytes.NewReader(tt.content)), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if whole := Bytes(tt.content, opts); got != whole {
+				t.Errorf("one byte reads count %+v, a single write %+v", got, whole)
+			}
+			if got.Bytes != int64(len(tt.content)) {
+				t.Errorf("Bytes = %d, want the %d raw bytes", got.Bytes, len(tt.content))
+			}
+			got.Bytes = want.Bytes
+			if got != want {
+				t.Errorf("counts = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodingUTF8KeepsBOM(t *testing.T) {
+	content := append(bytes.Clone(bomUTF8), "a b\n"...)
+	if got := Bytes(content, Options{}); got.Chars != 5 || got.Words != 2 {
+		t.Errorf("chars, words = %d, %d, want 5, 2", got.Chars, got.Words)
+	}
+	if got := Bytes(content, Options{Encoding: EncodingUTF8}); got.Chars != 5 {
+		t.Errorf("utf-8 chars = %d, want 5", got.Chars)
+	}
+}
+
+func TestEncodingLatin1(t *testing.T) {
+	got := Bytes([]byte("caf\xe9 cr\xe8me\n"), Options{Encoding: EncodingLatin1})
+	if got.Chars != 11 || got.Words != 2 || got.MaxLineLength != 10 {
+		t.Errorf("chars, words, width = %d, %d, %d, want 11, 2, 10", got.Chars, got.Words, got.MaxLineLength)
+	}
+}
+
+func TestEncodingUTF16Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		chars   int64
+	}{
+		{"odd byte", []byte{'a', 0, 'b'}, 2},
+		{"unpaired high surrogate", []byte{0x3d, 0xd8, 'a', 0}, 2},
+		{"unpaired high surrogate at the end", []byte{'a', 0, 0x3d, 0xd8}, 2},
+		{"unpaired low surrogate", []byte{0x89, 0xdf, 'a', 0}, 2},
+		{"two high surrogates then a pair", []byte{0x3d, 0xd8, 0x3d, 0xd8, 0x89, 0xdf}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Encoding: EncodingUTF16LE}
+			if got := Bytes(tt.content, opts); got.Chars != tt.chars {
+				t.Errorf("Chars = %d, want %d", got.Chars, tt.chars)
+			}
+			opts.Invalid = InvalidSkip
+			if got := Bytes(tt.content, opts); got.Chars != 1 {
+				t.Errorf("Chars skipping invalid = %d, want 1", got.Chars)
+			}
+		})
+	}
+}
+
+func TestEncodingShortInputs(t *testing.T) {
+	for _, content := range []string{"", "\xef", "\xef\xbb", "\xff", "a"} {
+		got := Bytes([]byte(content), Options{Encoding: EncodingAuto})
+		want := Bytes([]byte(content), Options{})
+		if got != want {
+			t.Errorf("%q counts %+v, want %+v", content, got, want)
+		}
+	}
+}
+
+func TestEncodingReaderAt(t *testing.T) {
+	content := utf16Bytes(strings.Repeat("héllo wörld\n", 500), false, true)
+	opts := Options{Encoding: EncodingAuto}
+	got, err := ReaderAt(bytes.NewReader(content), int64(len(content)), 8, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Bytes(content, opts); got != want {
+		t.Errorf("ReaderAt = %+v, want %+v", got, want)
+	}
+}
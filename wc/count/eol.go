@@ -0,0 +1,59 @@
+package count
+
+import "fmt"
+
+// EOL selects the line terminators counted as lines, the zero value counts
+// line feeds like wc. It implements flag.Value.
+type EOL string
+
+const (
+	EOLLF   EOL = "lf"   // every line feed, including the one of CRLF
+	EOLCRLF EOL = "crlf" // carriage returns followed by a line feed
+	EOLCR   EOL = "cr"   // carriage returns not followed by a line feed
+	EOLAny  EOL = "any"  // lone line feeds, CRLF and lone carriage returns
+)
+
+func (e *EOL) String() string {
+	return string(*e)
+}
+
+func (e *EOL) Set(value string) error {
+	switch eol := EOL(value); eol {
+	case EOLLF, EOLCRLF, EOLCR, EOLAny:
+		*e = eol
+		return nil
+	default:
+		return fmt.Errorf("invalid line ending %q, expected lf, crlf, cr or any", value)
+	}
+}
+
+// lines returns the number of lines of r ended by the selected terminators
+func (e EOL) lines(r Result) int64 {
+	switch e {
+	case EOLCRLF:
+		return r.CRLF
+	case EOLCR:
+		return r.CR
+	case EOLAny:
+		return r.LF + r.CRLF + r.CR
+	default:
+		return r.LF + r.CRLF
+	}
+}
+
+// Style returns the line ending style of r: "lf", "crlf" or "cr" when every
+// line ends the same way, "mixed" otherwise and "none" without line endings
+func (r Result) Style() string {
+	switch {
+	case r.LF == 0 && r.CRLF == 0 && r.CR == 0:
+		return "none"
+	case r.CRLF == 0 && r.CR == 0:
+		return "lf"
+	case r.LF == 0 && r.CR == 0:
+		return "crlf"
+	case r.LF == 0 && r.CRLF == 0:
+		return "cr"
+	default:
+		return "mixed"
+	}
+}
@@ -0,0 +1,66 @@
+package count
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEOL(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		lf, crlf, cr int64
+		any          int64
+		style        string
+		unterminated int64
+	}{
+		{"empty", "", 0, 0, 0, 0, "none", 0},
+		{"no line ending", "abc", 0, 0, 0, 0, "none", 1},
+		{"lf", "a\nb\n", 2, 0, 0, 2, "lf", 0},
+		{"crlf", "a\r\nb\r\nc", 2, 2, 0, 2, "crlf", 1},
+		{"cr", "a\rb\rc\r", 0, 0, 3, 3, "cr", 0},
+		{"mixed", "a\nb\r\nc\r", 2, 1, 1, 3, "mixed", 0},
+		{"blank lines", "\r\r\n\n\r", 2, 1, 2, 4, "mixed", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, mode := range []struct {
+				eol  EOL
+				want int64
+			}{{EOLLF, tt.lf}, {EOLCRLF, tt.crlf}, {EOLCR, tt.cr}, {EOLAny, tt.any}} {
+				got, err := Reader(iotest.OneByteReader(strings.NewReader(tt.content)), Options{EOL: mode.eol})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Lines != mode.want {
+					t.Errorf("%s lines = %d, want %d", mode.eol, got.Lines, mode.want)
+				}
+				if got.Style() != tt.style {
+					t.Errorf("Style() = %q, want %q", got.Style(), tt.style)
+				}
+				if got.Unterminated != tt.unterminated {
+					t.Errorf("Unterminated = %d, want %d", got.Unterminated, tt.unterminated)
+				}
+			}
+		})
+	}
+}
+
+func TestEOLMergeCRLF(t *testing.T) {
+	content := []byte("a\r\nb\rc\r")
+	want := Bytes(content, Options{EOL: EOLAny})
+
+	for split := range len(content) + 1 {
+		c := NewCounter(Options{EOL: EOLAny})
+		_, _ = c.Write(content[:split])
+		next := NewCounter(Options{EOL: EOLAny})
+		_, _ = next.Write(content[split:])
+		c.Merge(next)
+
+		if got := c.Result(); got != want {
+			t.Errorf("merge at %d = %+v, want %+v", split, got, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package count
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FrequencyOptions selects the words whose occurrences are counted
+type FrequencyOptions struct {
+	Fold      bool            // count words case insensitively, in lower case
+	MinLength int             // words of fewer characters are not counted
+	StopWords map[string]bool // words that are not counted, after folding
+}
+
+// word returns how a word is counted, or "" when it is not counted. The
+// punctuation around a word is not part of it, so "end." counts as "end".
+func (o *FrequencyOptions) word(raw []byte) string {
+	word := strings.TrimFunc(string(raw), unicode.IsPunct)
+	if o.Fold {
+		word = strings.ToLower(word)
+	}
+	if word == "" || utf8.RuneCountInString(word) < o.MinLength || o.StopWords[word] {
+		return ""
+	}
+	return word
+}
+
+// Frequencies holds the number of occurrences of every word
+type Frequencies struct {
+	counts map[string]int64
+}
+
+// NewFrequencies returns empty frequencies
+func NewFrequencies() *Frequencies {
+	return &Frequencies{counts: make(map[string]int64)}
+}
+
+func (f *Frequencies) addWord(word string, n int64) {
+	if word != "" {
+		f.counts[word] += n
+	}
+}
+
+// Add accumulates the occurrences of other into f
+func (f *Frequencies) Add(other *Frequencies) {
+	for word, n := range other.counts {
+		f.counts[word] += n
+	}
+}
+
+// Len returns the number of distinct words
+func (f *Frequencies) Len() int {
+	return len(f.counts)
+}
+
+// Count returns the number of occurrences of word
+func (f *Frequencies) Count(word string) int64 {
+	return f.counts[word]
+}
+
+// WordCount is a word and its number of occurrences
+type WordCount struct {
+	Word  string
+	Count int64
+}
+
+// Top returns the n most frequent words, most frequent first and in lexical
+// order when they occur as often, or every word when n is not positive
+func (f *Frequencies) Top(n int) []WordCount {
+	words := make([]WordCount, 0, len(f.counts))
+	for word, count := range f.counts {
+		words = append(words, WordCount{word, count})
+	}
+	slices.SortFunc(words, func(a, b WordCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Word, b.Word)
+	})
+	if n > 0 && n < len(words) {
+		words = words[:n]
+	}
+	return words
+}
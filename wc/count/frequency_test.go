@@ -0,0 +1,133 @@
+package count
+
+import (
+	"slices"
+	"testing"
+	"unicode/utf8"
+)
+
+func frequencyOptions() Options {
+	return Options{Invalid: InvalidCount, Frequencies: &FrequencyOptions{}}
+}
+
+func TestFrequencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    FrequencyOptions
+		want    []WordCount
+	}{
+		{"plain", "b a b\nc b a", FrequencyOptions{}, []WordCount{{"b", 3}, {"a", 2}, {"c", 1}}},
+		{"punctuation", "end. (end) end, don't", FrequencyOptions{}, []WordCount{{"end", 3}, {"don't", 1}}},
+		{"case sensitive", "The the THE", FrequencyOptions{}, []WordCount{{"THE", 1}, {"The", 1}, {"the", 1}}},
+		{"fold", "The the THE Été été", FrequencyOptions{Fold: true}, []WordCount{{"the", 3}, {"été", 2}}},
+		{"min length", "a bb ccc été", FrequencyOptions{MinLength: 3}, []WordCount{{"ccc", 1}, {"été", 1}}},
+		{"stop words", "the cat THE dog", FrequencyOptions{Fold: true, StopWords: map[string]bool{"the": true}},
+			[]WordCount{{"cat", 1}, {"dog", 1}}},
+		{"only punctuation", "-- ... x", FrequencyOptions{}, []WordCount{{"x", 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCounter(Options{Frequencies: &tt.opts})
+			_, _ = c.Write([]byte(tt.content))
+			got := c.Frequencies().Top(0)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Top() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Top() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFrequenciesTop(t *testing.T) {
+	c := NewCounter(frequencyOptions())
+	_, _ = c.Write([]byte("c b a b c c d"))
+
+	got := c.Frequencies().Top(2)
+	want := []WordCount{{"c", 3}, {"b", 2}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Top(2) = %v, want %v", got, want)
+	}
+	if words := c.Frequencies().Len(); words != 4 {
+		t.Errorf("Len() = %d, want 4", words)
+	}
+}
+
+func TestFrequenciesDisabled(t *testing.T) {
+	c := NewCounter(defaultOptions)
+	_, _ = c.Write([]byte("hello world"))
+	if f := c.Frequencies(); f != nil {
+		t.Errorf("Frequencies() = %v, want nil", f)
+	}
+}
+
+func TestResultDoesNotChangeCounter(t *testing.T) {
+	for _, words := range []WordRule{WordsUnicode, WordsUAX29} {
+		opts := frequencyOptions()
+		opts.Words = words
+		opts.Stats = true
+		content := []byte("abc\xe2def ghi\n")
+
+		c := NewCounter(opts)
+		_, _ = c.Write(content[:4])
+		// the incomplete rune is flushed by copies of the counter
+		_ = c.Result()
+		_ = c.Frequencies()
+		_ = c.LineStats()
+		_, _ = c.Write(content[4:])
+
+		want := NewCounter(opts)
+		_, _ = want.Write(content)
+		if got, want := c.Frequencies().Top(10), want.Frequencies().Top(10); !slices.Equal(got, want) {
+			t.Errorf("%s: frequencies after Result() = %v, want %v", words, got, want)
+		}
+		if got, want := c.LineStats(), want.LineStats(); got.Lines != want.Lines || got.Sum != want.Sum {
+			t.Errorf("%s: line stats after Result() = %+v, want %+v", words, got, want)
+		}
+	}
+}
+
+func TestFrequenciesMergeMatchesSerial(t *testing.T) {
+	for name, content := range chunkCorpus(t) {
+		t.Run(name, func(t *testing.T) {
+			serial := NewCounter(frequencyOptions())
+			_, _ = serial.Write(content)
+			want := serial.Frequencies()
+
+			step := max(len(content)/40, 1)
+			for i := 0; i <= len(content); i += step {
+				for j := i; j <= len(content); j += step {
+					if !runeStart(content, i) || !runeStart(content, j) {
+						continue
+					}
+					c := NewCounter(frequencyOptions())
+					_, _ = c.Write(content[:i])
+					for _, part := range [][]byte{content[i:j], content[j:]} {
+						next := NewCounter(frequencyOptions())
+						_, _ = next.Write(part)
+						c.Merge(next)
+					}
+
+					got := c.Frequencies()
+					if got.Len() != want.Len() {
+						t.Fatalf("merge at %d and %d has %d words, want %d", i, j, got.Len(), want.Len())
+					}
+					for _, word := range want.Top(0) {
+						if n := got.Count(word.Word); n != word.Count {
+							t.Fatalf("merge at %d and %d counts %q %d times, want %d", i, j, word.Word, n, word.Count)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func runeStart(content []byte, i int) bool {
+	return i == len(content) || utf8.RuneStart(content[i])
+}
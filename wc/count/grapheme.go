@@ -0,0 +1,190 @@
+package count
+
+import (
+	"sort"
+	"unicode"
+)
+
+// graphemeClass is the Grapheme_Cluster_Break property of a rune, as used by
+// the extended grapheme cluster rules of Unicode Standard Annex #29
+type graphemeClass int
+
+const (
+	gcOther graphemeClass = iota
+	gcCR
+	gcLF
+	gcControl
+	gcExtend
+	gcZWJ
+	gcRegionalIndicator
+	gcPrepend
+	gcSpacingMark
+	gcL
+	gcV
+	gcT
+	gcLV
+	gcLVT
+	gcExtendedPictographic
+)
+
+// runeRange is an inclusive range of code points
+type runeRange struct {
+	lo, hi rune
+}
+
+// inRanges reports whether r is in one of the sorted ranges
+func inRanges(r rune, ranges []runeRange) bool {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].hi >= r })
+	return i < len(ranges) && ranges[i].lo <= r
+}
+
+// prependRanges are the format characters that attach to the following rune
+var prependRanges = []runeRange{
+	{0x0600, 0x0605}, {0x06DD, 0x06DD}, {0x070F, 0x070F}, {0x0890, 0x0891},
+	{0x08E2, 0x08E2}, {0x110BD, 0x110BD}, {0x110CD, 0x110CD},
+}
+
+// extendedPictographicRanges approximates the Extended_Pictographic property,
+// which covers emoji and the symbols that may become emoji
+var extendedPictographicRanges = []runeRange{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA},
+	{0x231A, 0x231B}, {0x2328, 0x2328}, {0x2388, 0x2388}, {0x23CF, 0x23CF},
+	{0x23E9, 0x23F3}, {0x23F8, 0x23FA}, {0x24C2, 0x24C2}, {0x25AA, 0x25AB},
+	{0x25B6, 0x25B6}, {0x25C0, 0x25C0}, {0x25FB, 0x25FE}, {0x2600, 0x27BF},
+	{0x2934, 0x2935}, {0x2B05, 0x2B07}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50},
+	{0x2B55, 0x2B55}, {0x3030, 0x3030}, {0x303D, 0x303D}, {0x3297, 0x3297},
+	{0x3299, 0x3299}, {0x1F000, 0x1F0FF}, {0x1F10D, 0x1F10F}, {0x1F12F, 0x1F12F},
+	{0x1F16C, 0x1F171}, {0x1F17E, 0x1F17F}, {0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A},
+	{0x1F1AD, 0x1F1E5}, {0x1F201, 0x1F20F}, {0x1F21A, 0x1F21A}, {0x1F22F, 0x1F22F},
+	{0x1F232, 0x1F23A}, {0x1F23C, 0x1F23F}, {0x1F249, 0x1F3FA}, {0x1F400, 0x1F53D},
+	{0x1F546, 0x1F64F}, {0x1F680, 0x1F6FF}, {0x1F774, 0x1F77F}, {0x1F7D5, 0x1F7FF},
+	{0x1F80C, 0x1F80F}, {0x1F848, 0x1F84F}, {0x1F85A, 0x1F85F}, {0x1F888, 0x1F88F},
+	{0x1F8AE, 0x1F8FF}, {0x1F90C, 0x1F93A}, {0x1F93C, 0x1F945}, {0x1F947, 0x1FAFF},
+	{0x1FC00, 0x1FFFD},
+}
+
+func graphemeClassOf(r rune) graphemeClass {
+	switch {
+	case r == '\r':
+		return gcCR
+	case r == '\n':
+		return gcLF
+	case r < 0x7F:
+		if r < 0x20 {
+			return gcControl
+		}
+		return gcOther
+	case r == 0x200D:
+		return gcZWJ
+	case r == 0x200C, r >= 0x1F3FB && r <= 0x1F3FF, r >= 0xE0020 && r <= 0xE007F:
+		// zero width non-joiner, emoji skin tone modifiers and emoji tags
+		return gcExtend
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return gcRegionalIndicator
+	case inRanges(r, prependRanges):
+		return gcPrepend
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return gcExtend
+	case unicode.Is(unicode.Mc, r):
+		return gcSpacingMark
+	case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp):
+		return gcControl
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gcL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gcV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gcT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		// precomposed syllables without a trailing consonant are LV
+		if (r-0xAC00)%28 == 0 {
+			return gcLV
+		}
+		return gcLVT
+	case inRanges(r, extendedPictographicRanges):
+		return gcExtendedPictographic
+	default:
+		return gcOther
+	}
+}
+
+// graphemeBreaker finds extended grapheme cluster boundaries in a stream of
+// runes, so emoji sequences and combining accents count as one character.
+// The zero value is ready to use.
+type graphemeBreaker struct {
+	started bool
+	prev    graphemeClass
+	// number of consecutive regional indicators before the current rune
+	regionalIndicators int
+	// an extended pictographic followed by extends, which a ZWJ may join to
+	// the next pictographic
+	inPictographic  bool
+	pictographicZWJ bool
+}
+
+// reset forgets the previous runes, the next rune starts a new cluster
+func (g *graphemeBreaker) reset() {
+	*g = graphemeBreaker{}
+}
+
+// next reports whether there is a cluster boundary before r
+func (g *graphemeBreaker) next(r rune) bool {
+	class := graphemeClassOf(r)
+	boundary := !g.started || g.isBoundary(class)
+
+	switch class {
+	case gcRegionalIndicator:
+		g.regionalIndicators++
+	default:
+		g.regionalIndicators = 0
+	}
+	switch class {
+	case gcExtendedPictographic:
+		g.inPictographic = true
+		g.pictographicZWJ = false
+	case gcExtend:
+		g.pictographicZWJ = false
+	case gcZWJ:
+		g.pictographicZWJ = g.inPictographic
+		g.inPictographic = false
+	default:
+		g.inPictographic = false
+		g.pictographicZWJ = false
+	}
+	g.started = true
+	g.prev = class
+
+	return boundary
+}
+
+// isBoundary applies the rules GB3 to GB999 between the previous rune and a
+// rune of the given class
+func (g *graphemeBreaker) isBoundary(class graphemeClass) bool {
+	prev := g.prev
+	switch {
+	case prev == gcCR && class == gcLF:
+		return false
+	case prev == gcCR, prev == gcLF, prev == gcControl:
+		return true
+	case class == gcCR, class == gcLF, class == gcControl:
+		return true
+	case prev == gcL && (class == gcL || class == gcV || class == gcLV || class == gcLVT):
+		return false
+	case (prev == gcLV || prev == gcV) && (class == gcV || class == gcT):
+		return false
+	case (prev == gcLVT || prev == gcT) && class == gcT:
+		return false
+	case class == gcExtend, class == gcZWJ, class == gcSpacingMark:
+		return false
+	case prev == gcPrepend:
+		return false
+	case prev == gcZWJ && class == gcExtendedPictographic && g.pictographicZWJ:
+		return false
+	case prev == gcRegionalIndicator && class == gcRegionalIndicator:
+		// flags are pairs of regional indicators
+		return g.regionalIndicators%2 == 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,40 @@
+package count
+
+import (
+	"bytes"
+	"regexp"
+	"unicode/utf8"
+)
+
+// maxMatchLine is the longest part of a line that is matched, the matches in
+// the rest of a longer line are not counted
+const maxMatchLine = BufferSize
+
+// matchRune collects the current line, only its first maxMatchLine bytes,
+// and counts the matches of the pattern in it once its line feed is read.
+// The carriage return of a CRLF is not part of the line, so $ matches before
+// it.
+func (c *Counter) matchRune(r rune, crlf bool) {
+	if r != '\n' {
+		if len(c.matchLine) < maxMatchLine {
+			c.matchLine = utf8.AppendRune(c.matchLine, r)
+		}
+		return
+	}
+	line := c.matchLine
+	if crlf && bytes.HasSuffix(line, []byte{'\r'}) {
+		line = line[:len(line)-1]
+	}
+	countMatches(c.opts.Pattern, line, &c.counts)
+	c.matchLine = c.matchLine[:0]
+}
+
+// countMatches adds the non-overlapping matches of pattern in line to the
+// matches of result, and the line to the matching lines when there is one
+func countMatches(pattern *regexp.Regexp, line []byte, result *Result) {
+	matches := len(pattern.FindAllIndex(line, -1))
+	result.Matches += int64(matches)
+	if matches > 0 {
+		result.MatchingLines++
+	}
+}
@@ -0,0 +1,75 @@
+package count
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"testing/iotest"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern       string
+		content       string
+		matches, line int64
+	}{
+		{"empty", "a", "", 0, 0},
+		{"several per line", "o", "foo\nbar\nboo\n", 4, 2},
+		{"no final newline", "r$", "foo\nbar", 1, 1},
+		{"crlf", "r$", "bar\r\nbar\r\n", 2, 2},
+		{"lone carriage return", `a\rb`, "a\rb\n", 1, 1},
+		{"empty lines", "^$", "a\n\n\nb\n", 2, 2},
+		{"multibyte", "é+", "été\n", 2, 1},
+		{"alternatives", "(?:foo)|(?:o+)", "foo\nboo\n", 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Pattern: regexp.MustCompile(tt.pattern)}
+			got, err := Reader(iotest.OneByteReader(bytes.NewReader([]byte(tt.content))), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Matches != tt.matches || got.MatchingLines != tt.line {
+				t.Errorf("matches, matching lines = %d, %d, want %d, %d", got.Matches, got.MatchingLines, tt.matches, tt.line)
+			}
+			if whole := Bytes([]byte(tt.content), opts); whole != got {
+				t.Errorf("a single write counts %+v, one byte writes %+v", whole, got)
+			}
+		})
+	}
+}
+
+func TestMatchesLongLine(t *testing.T) {
+	opts := Options{Pattern: regexp.MustCompile(`x|y$`)}
+	long := append(append([]byte("x"), bytes.Repeat([]byte(" "), 2*maxMatchLine)...), "x\n"...)
+	// the carriage return of the CRLF is the last byte kept
+	full := append(bytes.Repeat([]byte(" "), maxMatchLine-2), "y\r\n"...)
+	content := append(append(long, full...), "x\n"...)
+
+	got := Bytes(content, opts)
+	if got.Matches != 3 || got.MatchingLines != 3 {
+		t.Errorf("matches, matching lines = %d, %d, want 3, 3", got.Matches, got.MatchingLines)
+	}
+
+	c := NewCounter(opts)
+	for range 4 {
+		_, _ = c.Write(bytes.Repeat([]byte("x"), maxMatchLine))
+	}
+	if len(c.matchLine) > maxMatchLine {
+		t.Errorf("kept %d bytes of a line without line feed, want at most %d", len(c.matchLine), maxMatchLine)
+	}
+}
+
+func TestMatchesReaderAt(t *testing.T) {
+	content := bytes.Repeat([]byte("error: disk full\r\nok\nerror, error\n"), 1000)
+	opts := Options{Pattern: regexp.MustCompile(`error\b`)}
+	got, err := ReaderAt(bytes.NewReader(content), int64(len(content)), 8, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Bytes(content, opts); got != want || got.Matches != 3000 || got.MatchingLines != 2000 {
+		t.Errorf("ReaderAt = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package count
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const (
+	// exactLengths is the number of line lengths counted exactly, longer
+	// lines are counted in buckets
+	exactLengths = 1024
+	// bucketGrowth is the ratio between the bounds of a bucket, so a length
+	// estimated from its bucket is off by less than 1%
+	bucketGrowth = 1.02
+)
+
+// LineStats is the distribution of the line lengths of an input, in bytes
+// without the line terminator. Lines are ended by line feeds and the last
+// line counts even without one.
+//
+// Lengths shorter than 1024 bytes are counted exactly. Longer ones are
+// counted in buckets growing by 2%, so the memory stays bounded, under 24 KiB,
+// whatever the number of lines, and a quantile is within 1% of the exact
+// one.
+type LineStats struct {
+	Lines int64
+	Min   int64
+	Max   int64
+	Sum   int64
+	// number of lines of each length, then of each bucket of lengths
+	buckets []int64
+}
+
+// NewLineStats returns the statistics of no lines
+func NewLineStats() *LineStats {
+	return &LineStats{}
+}
+
+func bucketOf(length int64) int {
+	if length < exactLengths {
+		return int(length)
+	}
+	return exactLengths + int(math.Log(float64(length)/exactLengths)/math.Log(bucketGrowth))
+}
+
+// bucketBounds returns the smallest and largest length of a bucket
+func bucketBounds(bucket int) (int64, int64) {
+	if bucket < exactLengths {
+		return int64(bucket), int64(bucket)
+	}
+	lower := int64(math.Ceil(exactLengths * math.Pow(bucketGrowth, float64(bucket-exactLengths))))
+	upper := int64(math.Ceil(exactLengths*math.Pow(bucketGrowth, float64(bucket-exactLengths+1)))) - 1
+	return lower, max(lower, upper)
+}
+
+func (s *LineStats) add(length int64) {
+	if s.Lines == 0 || length < s.Min {
+		s.Min = length
+	}
+	s.Max = max(s.Max, length)
+	s.Lines++
+	s.Sum += length
+
+	bucket := bucketOf(length)
+	if bucket >= len(s.buckets) {
+		s.buckets = slices.Grow(s.buckets, bucket+1-len(s.buckets))[:bucket+1]
+	}
+	s.buckets[bucket]++
+}
+
+// Add accumulates the lines of other into s
+func (s *LineStats) Add(other *LineStats) {
+	if other.Lines == 0 {
+		return
+	}
+	if s.Lines == 0 || other.Min < s.Min {
+		s.Min = other.Min
+	}
+	s.Max = max(s.Max, other.Max)
+	s.Lines += other.Lines
+	s.Sum += other.Sum
+
+	if len(other.buckets) > len(s.buckets) {
+		s.buckets = slices.Grow(s.buckets, len(other.buckets)-len(s.buckets))[:len(other.buckets)]
+	}
+	for i, n := range other.buckets {
+		s.buckets[i] += n
+	}
+}
+
+func (s *LineStats) clone() *LineStats {
+	c := *s
+	c.buckets = slices.Clone(s.buckets)
+	return &c
+}
+
+// Mean returns the average line length, 0 without lines
+func (s *LineStats) Mean() float64 {
+	if s.Lines == 0 {
+		return 0
+	}
+	return float64(s.Sum) / float64(s.Lines)
+}
+
+// Quantile returns the smallest length such that a fraction q of the lines
+// are not longer, such as the median for 0.5, or 0 without lines
+func (s *LineStats) Quantile(q float64) int64 {
+	if s.Lines == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(q*float64(s.Lines))), 1)
+	var seen int64
+	for bucket, n := range s.buckets {
+		seen += n
+		if seen >= rank {
+			lower, upper := bucketBounds(bucket)
+			return min(max((lower+upper)/2, s.Min), s.Max)
+		}
+	}
+	return s.Max
+}
+
+// Bin is a range of line lengths and the number of lines in it
+type Bin struct {
+	Min   int64
+	Max   int64
+	Lines int64
+}
+
+// Histogram returns the number of lines by ranges of lengths doubling in
+// size: 0, 1, 2-3, 4-7 and so on up to the range of the longest line. Long
+// lines are put in a range by the middle of their bucket.
+func (s *LineStats) Histogram() []Bin {
+	if s.Lines == 0 {
+		return nil
+	}
+	bins := []Bin{{0, 0, 0}}
+	for bins[len(bins)-1].Max < s.Max {
+		lower := bins[len(bins)-1].Max + 1
+		bins = append(bins, Bin{lower, 2*lower - 1, 0})
+	}
+	for bucket, n := range s.buckets {
+		if n == 0 {
+			continue
+		}
+		lower, upper := bucketBounds(bucket)
+		length := min((lower+upper)/2, s.Max)
+		bins[bits.Len64(uint64(length))].Lines += n
+	}
+	return bins
+}
@@ -0,0 +1,123 @@
+package count
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func statsOf(content string) *LineStats {
+	c := NewCounter(Options{Stats: true})
+	_, _ = c.Write([]byte(content))
+	return c.LineStats()
+}
+
+func TestLineStats(t *testing.T) {
+	tests := []struct {
+		name                 string
+		content              string
+		lines, min, max, sum int64
+	}{
+		{"empty", "", 0, 0, 0, 0},
+		{"empty line", "\n", 1, 0, 0, 0},
+		{"no final newline", "ab\nabcd", 2, 2, 4, 6},
+		{"crlf", "ab\r\n\r\nabc\r\n", 3, 0, 3, 5},
+		{"lone carriage return", "a\rb\n", 1, 3, 3, 3},
+		{"multibyte", "été\n", 1, 5, 5, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := statsOf(tt.content)
+			if got.Lines != tt.lines || got.Min != tt.min || got.Max != tt.max || got.Sum != tt.sum {
+				t.Errorf("lines, min, max, sum = %d, %d, %d, %d, want %d, %d, %d, %d",
+					got.Lines, got.Min, got.Max, got.Sum, tt.lines, tt.min, tt.max, tt.sum)
+			}
+		})
+	}
+}
+
+func TestLineStatsQuantiles(t *testing.T) {
+	var content strings.Builder
+	for length := 1; length <= 100; length++ {
+		content.WriteString(strings.Repeat("x", length) + "\n")
+	}
+	s := statsOf(content.String())
+
+	if mean := s.Mean(); mean != 50.5 {
+		t.Errorf("Mean() = %v, want 50.5", mean)
+	}
+	for _, q := range []struct {
+		q    float64
+		want int64
+	}{{0, 1}, {0.5, 50}, {0.9, 90}, {0.99, 99}, {1, 100}} {
+		if got := s.Quantile(q.q); got != q.want {
+			t.Errorf("Quantile(%v) = %d, want %d", q.q, got, q.want)
+		}
+	}
+	if got := NewLineStats().Quantile(0.5); got != 0 {
+		t.Errorf("Quantile() without lines = %d, want 0", got)
+	}
+}
+
+func TestLineStatsLongLines(t *testing.T) {
+	s := NewLineStats()
+	for length := int64(1000); length <= 1_000_000; length += 7 {
+		s.add(length)
+	}
+
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		exact := float64(1000 + int64(q*float64(s.Lines-1))*7)
+		got := float64(s.Quantile(q))
+		if got < exact*0.99 || got > exact*1.01 {
+			t.Errorf("Quantile(%v) = %v, want within 1%% of %v", q, got, exact)
+		}
+	}
+	if size := len(s.buckets); size > exactLengths+400 {
+		t.Errorf("%d buckets for lines up to 1 MB", size)
+	}
+}
+
+func TestLineStatsHistogram(t *testing.T) {
+	got := statsOf("\na\nabc\nabcd\nabcdefg\n").Histogram()
+	want := []Bin{{0, 0, 1}, {1, 1, 1}, {2, 3, 1}, {4, 7, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Histogram() = %v, want %v", got, want)
+	}
+	if got := NewLineStats().Histogram(); got != nil {
+		t.Errorf("Histogram() without lines = %v, want nil", got)
+	}
+}
+
+func TestLineStatsMergeMatchesSerial(t *testing.T) {
+	content := []byte("a\r\nbc\r\n\r\nlonger line\nx\r\rend")
+	want := statsOf(string(content))
+
+	for i := 0; i <= len(content); i++ {
+		for j := i; j <= len(content); j++ {
+			c := NewCounter(Options{Stats: true})
+			_, _ = c.Write(content[:i])
+			for _, part := range [][]byte{content[i:j], content[j:]} {
+				next := NewCounter(Options{Stats: true})
+				_, _ = next.Write(part)
+				c.Merge(next)
+			}
+			if got := c.LineStats(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("merge at %d and %d = %+v, want %+v", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestLineStatsReaderAt(t *testing.T) {
+	content := bytes.Repeat([]byte("short\nmuch longer line\r\n\n"), 1000)
+	opts := Options{Stats: true}
+	c, err := CounterAt(bytes.NewReader(content), int64(len(content)), 8, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.LineStats(), statsOf(string(content)); !reflect.DeepEqual(got, want) {
+		t.Errorf("CounterAt stats = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package count
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// WordRule selects how the input is split into words, the zero value splits
+// on Unicode whitespace. It implements flag.Value.
+type WordRule string
+
+const (
+	WordsPOSIX   WordRule = "posix"   // words are separated by ASCII whitespace, like GNU wc in the C locale
+	WordsUnicode WordRule = "unicode" // words are separated by Unicode whitespace, like strings.Fields
+	WordsUAX29   WordRule = "uax29"   // words are the UAX #29 segments containing a letter or a digit
+)
+
+func (w *WordRule) String() string {
+	return string(*w)
+}
+
+func (w *WordRule) Set(value string) error {
+	switch rule := WordRule(value); rule {
+	case WordsPOSIX, WordsUnicode, WordsUAX29:
+		*w = rule
+		return nil
+	default:
+		return fmt.Errorf("invalid word rule %q, expected posix, unicode or uax29", value)
+	}
+}
+
+// isSpace reports whether r separates words, for the rules that split on
+// whitespace
+func (w WordRule) isSpace(r rune) bool {
+	if w == WordsPOSIX {
+		return r == ' ' || r >= '\t' && r <= '\r'
+	}
+	return unicode.IsSpace(r)
+}
+
+// wordClass is the Word_Break property of a rune, as far as it matters for
+// segments containing letters and digits
+type wordClass int
+
+const (
+	wbOther wordClass = iota
+	wbCR
+	wbLF
+	wbNewline
+	wbExtend // Extend, Format and ZWJ
+	wbKatakana
+	wbHebrewLetter
+	wbALetter
+	wbSingleQuote
+	wbDoubleQuote
+	wbMidNumLet
+	wbMidLetter
+	wbMidNum
+	wbNumeric
+	wbExtendNumLet
+)
+
+// wordClassOf approximates the Word_Break property with the tables of the
+// unicode package. Scripts written without spaces, such as Thai, are letters
+// too: splitting them needs a dictionary, so a run of them is one word.
+func wordClassOf(r rune) wordClass {
+	switch r {
+	case '\r':
+		return wbCR
+	case '\n':
+		return wbLF
+	case '\v', '\f', 0x85, 0x2028, 0x2029:
+		return wbNewline
+	case '\'':
+		return wbSingleQuote
+	case '"':
+		return wbDoubleQuote
+	case '.', 0x2018, 0x2019, 0x2024, 0xFE52, 0xFF07, 0xFF0E:
+		return wbMidNumLet
+	case ':', 0xB7, 0x387, 0x55F, 0x5F4, 0x2027, 0xFE13, 0xFE55, 0xFF1A:
+		return wbMidLetter
+	case ',', ';', 0x37E, 0x589, 0x60C, 0x60D, 0x66C, 0x7F8, 0x2044, 0xFE10, 0xFE14, 0xFE50, 0xFE54, 0xFF0C, 0xFF1B:
+		return wbMidNum
+	case 0x200B:
+		// zero width space is not a format character for word breaks
+		return wbOther
+	case 0x202F:
+		return wbExtendNumLet
+	case 0x3031, 0x3032, 0x3033, 0x3034, 0x3035, 0x309B, 0x309C, 0x30A0, 0x30FC, 0xFF70:
+		return wbKatakana
+	}
+
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Cf), r == 0xFF9E, r == 0xFF9F:
+		return wbExtend
+	case unicode.Is(unicode.Katakana, r):
+		return wbKatakana
+	case unicode.Is(unicode.Hebrew, r) && unicode.IsLetter(r):
+		return wbHebrewLetter
+	case unicode.IsDigit(r) && (r < 0xFF10 || r > 0xFF19):
+		return wbNumeric
+	case unicode.Is(unicode.Pc, r):
+		return wbExtendNumLet
+	case unicode.In(r, unicode.Han, unicode.Hiragana):
+		// ideographs and hiragana are words on their own
+		return wbOther
+	case unicode.IsLetter(r), unicode.Is(unicode.Nl, r):
+		return wbALetter
+	default:
+		return wbOther
+	}
+}
+
+func isAHLetter(class wordClass) bool {
+	return class == wbALetter || class == wbHebrewLetter
+}
+
+func isMidLetterQ(class wordClass) bool {
+	return class == wbMidLetter || class == wbMidNumLet || class == wbSingleQuote
+}
+
+func isMidNumQ(class wordClass) bool {
+	return class == wbMidNum || class == wbMidNumLet || class == wbSingleQuote
+}
+
+// wordSegmenter finds the word boundaries of UAX #29 in a stream of runes.
+// WB6 and WB12 need to look one rune ahead, so no boundary is reported
+// before the punctuation in the middle of a word such as "can't" or "3.14",
+// and a boundary is reported before the next rune when it does not continue
+// the word. Segments are only told apart as far as it matters for the ones
+// containing letters and digits, the rules for emoji, spaces and regional
+// indicators are not needed for that.
+type wordSegmenter struct {
+	beforePrev wordClass
+	prev       wordClass
+}
+
+// next reports whether a segment starts at r
+func (s *wordSegmenter) next(r rune) bool {
+	class := wordClassOf(r)
+	if class == wbExtend && s.prev != wbCR && s.prev != wbLF && s.prev != wbNewline {
+		// WB4: extend and format characters belong to the previous rune
+		return false
+	}
+	boundary := isWordBoundary(s.beforePrev, s.prev, class)
+	s.beforePrev, s.prev = s.prev, class
+	return boundary
+}
+
+// isWordBoundary implements the rules WB3 to WB13b between prev and next,
+// preceded by beforePrev
+func isWordBoundary(beforePrev, prev, next wordClass) bool {
+	switch {
+	case prev == wbCR && next == wbLF:
+		return false // WB3
+	case prev == wbCR, prev == wbLF, prev == wbNewline, next == wbCR, next == wbLF, next == wbNewline:
+		return true // WB3a, WB3b
+	case isAHLetter(prev) && isAHLetter(next):
+		return false // WB5
+	case isAHLetter(prev) && isMidLetterQ(next), prev == wbHebrewLetter && next == wbDoubleQuote:
+		return false // WB6, WB7a and WB7b, decided by WB7 and WB7c with the next rune
+	case isAHLetter(beforePrev) && isMidLetterQ(prev) && isAHLetter(next):
+		return false // WB7
+	case beforePrev == wbHebrewLetter && prev == wbDoubleQuote && next == wbHebrewLetter:
+		return false // WB7c
+	case prev == wbNumeric && next == wbNumeric, isAHLetter(prev) && next == wbNumeric, prev == wbNumeric && isAHLetter(next):
+		return false // WB8, WB9, WB10
+	case beforePrev == wbNumeric && isMidNumQ(prev) && next == wbNumeric:
+		return false // WB11
+	case prev == wbNumeric && isMidNumQ(next):
+		return false // WB12, decided by WB11 with the next rune
+	case prev == wbKatakana && next == wbKatakana:
+		return false // WB13
+	case next == wbExtendNumLet && (isAHLetter(prev) || prev == wbNumeric || prev == wbKatakana || prev == wbExtendNumLet):
+		return false // WB13a
+	case prev == wbExtendNumLet && (isAHLetter(next) || next == wbNumeric || next == wbKatakana):
+		return false // WB13b
+	default:
+		return true // WB999
+	}
+}
+
+// isWordRune reports whether a segment containing r is a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
@@ -0,0 +1,68 @@
+package count
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWordRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		posix   int64
+		unicode int64
+		uax29   int64
+	}{
+		{"ascii", "hello world\nthis is a test\n", 6, 6, 6},
+		{"unicode spaces", "hello\u00a0world\u2003again\n", 1, 3, 3},
+		{"punctuation", "Hello, world! -- (quoted) ...", 5, 5, 3},
+		{"ideographs", "日本語のテキスト", 1, 1, 5},
+		{"contractions and numbers", "can't 3.14 1,000 e.g. a_b", 5, 5, 5},
+		{"hebrew quotes", "צה\"ל", 1, 1, 1},
+		{"combining accent", "café näive", 2, 2, 2},
+		{"letters and digits", "abc123 x-1", 2, 2, 3},
+		{"line breaks", "a\r\nb\rc\vd", 4, 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, rule := range []struct {
+				words WordRule
+				want  int64
+			}{{WordsPOSIX, tt.posix}, {WordsUnicode, tt.unicode}, {WordsUAX29, tt.uax29}} {
+				got, err := Reader(iotest.OneByteReader(strings.NewReader(tt.content)), Options{Words: rule.words})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Words != rule.want {
+					t.Errorf("%s words = %d, want %d", rule.words, got.Words, rule.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWordRuleFrequencies(t *testing.T) {
+	c := NewCounter(Options{Words: WordsUAX29, Frequencies: &FrequencyOptions{}})
+	_, _ = c.Write([]byte("(x) x.y 日本 _a x, x\n"))
+
+	for word, want := range map[string]int64{"x": 3, "x.y": 1, "日": 1, "本": 1, "a": 1} {
+		if got := c.Frequencies().Count(word); got != want {
+			t.Errorf("Count(%q) = %d, want %d", word, got, want)
+		}
+	}
+	if got := c.Frequencies().Len(); got != 5 {
+		t.Errorf("Len() = %d, want 5", got)
+	}
+}
+
+func TestWordRuleSet(t *testing.T) {
+	var rule WordRule
+	if err := rule.Set("uax29"); err != nil || rule != WordsUAX29 {
+		t.Errorf("Set(uax29) = %v, rule %q", err, rule)
+	}
+	if err := rule.Set("icu"); err == nil {
+		t.Error("Expected an error for an unknown word rule")
+	}
+}
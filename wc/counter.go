@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+
+	"wc/count"
+)
+
+// counts holds the totals of an input as printed by wc
+type counts struct {
+	count.Result
+	// size of the input before decompression
+	compressed int64
+	// occurrences of the words, with --top
+	words *count.Frequencies
+	// language of the lines of code, with --code
+	language string
+	// distribution of the line lengths, with --stats
+	stats *count.LineStats
+	// difference with a snapshot, with --diff
+	diff *countsDiff
+}
+
+// countsOf returns the counts of everything written to c
+func countsOf(c *count.Counter) counts {
+	return counts{Result: c.Result(), words: c.Frequencies(), stats: c.LineStats()}
+}
+
+// countReader counts r in a single pass
+func countReader(r io.Reader, opts count.Options) (counts, error) {
+	c := count.NewCounter(opts)
+	_, err := c.ReadFrom(r)
+	return countsOf(c), err
+}
+
+// countContent counts an in-memory buffer with the default options
+func countContent(content []byte) counts {
+	return counts{Result: count.Bytes(content, count.Options{Invalid: count.InvalidCount})}
+}
+
+// add accumulates other into c, used to compute the total row
+func (c *counts) add(other counts) {
+	c.Result.Add(other.Result)
+	switch {
+	case c.language == "":
+		c.language = other.language
+	case other.language != "" && other.language != c.language:
+		c.language = allLanguages
+	}
+	c.compressed += other.compressed
+	if other.words != nil {
+		if c.words == nil {
+			c.words = count.NewFrequencies()
+		}
+		c.words.Add(other.words)
+	}
+	if other.stats != nil {
+		if c.stats == nil {
+			c.stats = count.NewLineStats()
+		}
+		c.stats.Add(other.stats)
+	}
+}
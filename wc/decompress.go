@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+
+	"wc/count"
+)
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// compression is a compressed format detected by its magic bytes
+type compression int
+
+const (
+	compressionNone compression = iota
+	compressionGzip
+	compressionBzip2
+	compressionZlib
+)
+
+// zlibPrefix is how much of a stream starting with the magic bytes of zlib
+// is decompressed to tell it from text that starts the same way, such as
+// "x^2"
+const zlibPrefix = 512
+
+// detectCompression looks at the first bytes of a stream
+func detectCompression(magic []byte) compression {
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return compressionGzip
+	case bytes.HasPrefix(magic, []byte("BZh")) && len(magic) > 3 && magic[3] >= '1' && magic[3] <= '9':
+		return compressionBzip2
+	case len(magic) >= 2 && magic[0] == 0x78 && bytes.IndexByte([]byte{0x01, 0x5e, 0x9c, 0xda}, magic[1]) >= 0:
+		// deflate with a 32 KiB window at one of the usual compression levels
+		return compressionZlib
+	default:
+		return compressionNone
+	}
+}
+
+// decompress returns a reader of the decompressed content of r, or of r
+// itself when it is not compressed
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReaderSize(r, count.BufferSize)
+	// a short stream is simply not compressed
+	magic, _ := buffered.Peek(4)
+
+	switch detectCompression(magic) {
+	case compressionGzip:
+		return gzip.NewReader(buffered)
+	case compressionBzip2:
+		return bzip2.NewReader(buffered), nil
+	case compressionZlib:
+		prefix, _ := buffered.Peek(zlibPrefix)
+		if !isZlib(prefix) {
+			return buffered, nil
+		}
+		return zlib.NewReader(buffered)
+	default:
+		return buffered, nil
+	}
+}
+
+// isZlib reports whether prefix, the start of a stream, decompresses without
+// error as far as it goes
+func isZlib(prefix []byte) bool {
+	z, err := zlib.NewReader(bytes.NewReader(prefix))
+	if err != nil {
+		return false
+	}
+	_, err = io.Copy(io.Discard, z)
+	return err == nil || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// countCompressed counts the decompressed content of r, the compressed size
+// is the number of bytes read from r
+func countCompressed(r io.Reader, opts count.Options) (counts, error) {
+	raw := &countingReader{r: r}
+	stream, err := decompress(raw)
+	if err != nil {
+		return counts{}, err
+	}
+
+	c, err := countReader(stream, opts)
+	if err != nil {
+		return counts{}, err
+	}
+	// the decompressor may stop before the end of the input
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		return counts{}, err
+	}
+	c.compressed = raw.n
+
+	return c, nil
+}
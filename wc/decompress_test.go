@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bzip2Hello is "hello world\n" compressed with bzip2, the standard library
+// can only decompress it
+var bzip2Hello = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x4e, 0xec,
+	0xe8, 0x36, 0x00, 0x00, 0x02, 0x51, 0x80, 0x00, 0x10, 0x40, 0x00, 0x06,
+	0x44, 0x90, 0x80, 0x20, 0x00, 0x31, 0x06, 0x4c, 0x41, 0x01, 0xa7, 0xa9,
+	0xa5, 0x80, 0xbb, 0x94, 0x31, 0xf8, 0xbb, 0x92, 0x29, 0xc2, 0x84, 0x82,
+	0x77, 0x67, 0x41, 0xb0,
+}
+
+func gzipContent(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zlibContent(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCountCompressed(t *testing.T) {
+	content := strings.Repeat("hello world\n", 100)
+	gz := gzipContent(t, content)
+	zl := zlibContent(t, content)
+	var numbers strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&numbers, "%d\n", i*7919%10007)
+	}
+	long := numbers.String()
+	longZlib := zlibContent(t, long)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		content string
+	}{
+		{"gzip", gz, content},
+		{"concatenated gzip", append(append([]byte{}, gz...), gz...), content + content},
+		{"zlib", zl, content},
+		{"zlib longer than the checked prefix", longZlib, long},
+		{"bzip2", bzip2Hello, "hello world\n"},
+		{"plain", []byte(content), content},
+		{"short", []byte("x"), "x"},
+		// the first bytes are the magic bytes of zlib
+		{"text like zlib", []byte("x^2 + y^2 = z^2\n"), "x^2 + y^2 = z^2\n"},
+		{"binary like zlib", []byte("x\x01\x02\x03 data"), "x\x01\x02\x03 data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := countCompressed(bytes.NewReader(tt.input), defaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := countContent([]byte(tt.content))
+			want.compressed = int64(len(tt.input))
+			if got != want {
+				t.Errorf("countCompressed() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCountCompressedCorrupt(t *testing.T) {
+	gz := gzipContent(t, strings.Repeat("hello world\n", 100))
+	if _, err := countCompressed(bytes.NewReader(gz[:len(gz)/2]), defaultOptions); err == nil {
+		t.Error("Expected an error for a truncated gzip stream")
+	}
+}
+
+func TestRunDecompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.gz")
+	gz := gzipContent(t, "hello world\nagain\n")
+	if err := os.WriteFile(path, gz, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := runOutput(t, "-z", "-l", "-c", path)
+	// the counts of the content may be larger than the compressed file
+	want := fmt.Sprintf("%7d %7d %7d %s\n", 2, 18, len(gz), path)
+	if got != want {
+		t.Errorf("run() output = %q, want %q", got, want)
+	}
+
+	got = runWithStdin(t, string(gz), "--decompress", "-w")
+	if want := fmt.Sprintf("      3 %7d\n", len(gz)); got != want {
+		t.Errorf("run() output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+)
+
+// snapshot holds the counts of every file and of the total by column name,
+// it is written by --save and compared with the counts by --diff
+type snapshot struct {
+	Files map[string]map[string]int64 `json:"files"`
+	Total map[string]int64            `json:"total"`
+	// the saved counts
+	columns []column
+}
+
+func newSnapshot(columns []column) *snapshot {
+	return &snapshot{Files: make(map[string]map[string]int64), columns: columns}
+}
+
+// snapshotColumns returns the counts saved by --save: the counts printed by
+// default, -m and -L, so a later run can compare them, and the other
+// selected counts
+func (o *options) snapshotColumns() []column {
+	columns := []column{linesColumn, wordsColumn, charsColumn, bytesColumn, maxLineLengthColumn}
+	for _, col := range o.countColumns() {
+		saved := slices.ContainsFunc(columns, func(c column) bool { return c.name == col.name })
+		if col.label == nil && !saved {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// snapshotValues returns the counts of c by column name
+func snapshotValues(c counts, columns []column) map[string]int64 {
+	values := make(map[string]int64, len(columns))
+	for _, col := range columns {
+		values[col.name] = col.value(c)
+	}
+	return values
+}
+
+// add saves the counts of a file, s may be nil when not saving
+func (s *snapshot) add(res result) {
+	if s != nil {
+		s.Files[displayName(res.name)] = snapshotValues(res.counts, s.columns)
+	}
+}
+
+// readSnapshot reads a snapshot written by --save, which must hold every
+// count of columns
+func readSnapshot(filename string, columns []column) (*snapshot, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := newSnapshot(nil)
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	for _, col := range columns {
+		if col.label != nil {
+			continue
+		}
+		for _, values := range s.Files {
+			if _, ok := values[col.name]; !ok {
+				return nil, fmt.Errorf("%s has no %s counts", filename, col.name)
+			}
+		}
+		if _, ok := s.Total[col.name]; !ok {
+			return nil, fmt.Errorf("%s has no %s counts", filename, col.name)
+		}
+	}
+	return s, nil
+}
+
+// write saves the snapshot with the total counts to filename, indented so
+// it can be committed and compared line by line
+func (s *snapshot) write(filename string, total counts) error {
+	s.Total = snapshotValues(total, s.columns)
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(content, '\n'), 0o644)
+}
+
+// countsDiff is how counts differ from a snapshot, with --diff
+type countsDiff struct {
+	before map[string]int64 // the counts of the snapshot by column name
+	// 1 for the status of a file, the number of such files for the total
+	added   int64
+	removed int64
+	changed int64
+}
+
+// diffStatusColumns are the number of files added, removed and changed
+// since the snapshot, printed after the differences of the counts
+var diffStatusColumns = []column{
+	{"added", func(c counts) int64 { return c.diff.added }, nil, false},
+	{"removed", func(c counts) int64 { return c.diff.removed }, nil, false},
+	{"changed", func(c counts) int64 { return c.diff.changed }, nil, false},
+}
+
+// diffColumns returns the difference of each count of columns with the
+// snapshot, the texts are left out, followed by the status columns
+func diffColumns(columns []column) []column {
+	var diff []column
+	for _, col := range columns {
+		if col.label != nil {
+			continue
+		}
+		value := func(c counts) int64 { return col.value(c) - c.diff.before[col.name] }
+		diff = append(diff, column{col.name, value, nil, false})
+	}
+	return append(diff, diffStatusColumns...)
+}
+
+// diffReporter prints the files that differ from a snapshot through another
+// reporter: the counted files that are not in the snapshot are added, the
+// files of the snapshot that were not counted are removed and unchanged
+// files are not printed. Inputs that fail are only reported on stderr.
+type diffReporter struct {
+	reporter
+	before  *snapshot
+	columns []column // the compared counts
+	seen    map[string]bool
+	status  countsDiff // the number of files of each status
+	flushed bool       // the removed files were printed
+}
+
+func newDiffReporter(rep reporter, opts *options) *diffReporter {
+	return &diffReporter{reporter: rep, before: opts.before, columns: opts.countColumns(), seen: make(map[string]bool)}
+}
+
+func (r *diffReporter) file(res result) {
+	if res.err != nil {
+		return
+	}
+	name := displayName(res.name)
+	r.seen[name] = true
+
+	before, ok := r.before.Files[name]
+	diff := &countsDiff{before: before}
+	switch {
+	case !ok:
+		diff.added = 1
+		r.status.added++
+	case r.differs(res.counts, before):
+		diff.changed = 1
+		r.status.changed++
+	default:
+		return
+	}
+	res.counts.diff = diff
+	r.reporter.file(res)
+}
+
+// differs reports whether a compared count of c is not the one of before
+func (r *diffReporter) differs(c counts, before map[string]int64) bool {
+	for _, col := range r.columns {
+		if col.label == nil && col.value(c) != before[col.name] {
+			return true
+		}
+	}
+	return false
+}
+
+// printRemoved prints the files of the snapshot that were not counted, by
+// name, once all the files were counted
+func (r *diffReporter) printRemoved() {
+	if r.flushed {
+		return
+	}
+	r.flushed = true
+	for _, name := range slices.Sorted(maps.Keys(r.before.Files)) {
+		if r.seen[name] {
+			continue
+		}
+		r.status.removed++
+		diff := &countsDiff{before: r.before.Files[name], removed: 1}
+		r.reporter.file(result{name: name, counts: counts{diff: diff}})
+	}
+}
+
+func (r *diffReporter) total(c counts) {
+	r.printRemoved()
+	diff := r.status
+	diff.before = r.before.Total
+	c.diff = &diff
+	r.reporter.total(c)
+}
+
+// languageTotal prints nothing, the languages are not compared
+func (r *diffReporter) languageTotal(counts) {}
+
+func (r *diffReporter) close() error {
+	r.printRemoved()
+	return r.reporter.close()
+}
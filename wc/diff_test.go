@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSaveDiff(t *testing.T) {
+	paths := writeTestFiles(t, "a b\nc\n", "x\n", "gone\n")
+	snapshot := filepath.Join(t.TempDir(), "snapshot.json")
+	runOutput(t, "--save", snapshot, paths[0], paths[1], paths[2])
+
+	if err := os.WriteFile(paths[0], []byte("a b\nc\nmore words here\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	added := filepath.Join(filepath.Dir(paths[0]), "new.txt")
+	if err := os.WriteFile(added, []byte("new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// unchanged files are not printed, removed files follow the counted ones
+	want := fmt.Sprintf(" 1  3 16  0  0  1 %s\n 1  1  4  1  0  0 %s\n-1 -1 -5  0  1  0 %s\n 1  3 15  1  1  1 total\n",
+		paths[0], added, paths[2])
+	if got := runOutput(t, "--diff", snapshot, paths[0], paths[1], added); got != want {
+		t.Errorf("--diff output = %q, want %q", got, want)
+	}
+	want = `{"files":[` + "\n" + `{"path":` + fmt.Sprintf("%q", paths[0]) + `,"lines":1,"words":3,"chars":16,"bytes":16,"added":0,"removed":0,"changed":1}` + "\n" +
+		`],"total":{"lines":1,"words":3,"chars":16,"bytes":16,"added":0,"removed":0,"changed":1,"files":1,"errors":0}}` + "\n"
+	if got := runOutput(t, "-m", "--diff", snapshot, "--total=always", "--format=json", paths[0], paths[1], paths[2]); got != want {
+		t.Errorf("--format=json output = %q, want %q", got, want)
+	}
+
+	// the snapshot can be compared and saved again in the same run
+	runOutput(t, "--diff", snapshot, "--save", snapshot, paths[0], paths[1], added)
+	if got := runOutput(t, "--diff", snapshot, paths[0], paths[1], added); got != " 0  0  0  0  0  0 total\n" {
+		t.Errorf("--diff output after --save = %q, want only a total", got)
+	}
+}
+
+func TestRunDiffErrors(t *testing.T) {
+	paths := writeTestFiles(t, "text\n")
+	snapshot := filepath.Join(t.TempDir(), "snapshot.json")
+	runOutput(t, "--save", snapshot, paths[0])
+
+	for _, args := range [][]string{
+		{"--diff", filepath.Join(t.TempDir(), "missing.json"), paths[0]},
+		{"--diff", paths[0], paths[0]},
+		{"-e", "x", "--diff", snapshot, paths[0]},
+		{"--top", "3", "--diff", snapshot, paths[0]},
+		{"-f", "--save", snapshot, paths[0]},
+	} {
+		var stdout bytes.Buffer
+		if err := run(args, strings.NewReader(""), &stdout, io.Discard); err == nil {
+			t.Errorf("run(%v) returned no error", args)
+		}
+	}
+}
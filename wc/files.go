@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// isStdin reports whether filename refers to standard input, either because
+// no file was given or because it is "-"
+func isStdin(filename string) bool {
+	return filename == "" || filename == "-"
+}
+
+// countFile opens filename and counts it in a single pass, standard input is
+// read from stdin. When reading fails, the counts of the data read before the
+// error are returned with it. With --archive the files of an archive are
+// returned too, and the counts are their total.
+func countFile(filename string, stdin io.Reader, opts *options) (c counts, members []result, err error) {
+	if isStdin(filename) {
+		return countStream(stdin, opts)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return counts{}, nil, err
+	}
+	defer closeFile(file, &err)
+
+	if opts.cache != nil {
+		return opts.cache.count(filename, file, opts)
+	}
+	return countOpened(filename, file, opts)
+}
+
+// countOpened counts the open file filename
+func countOpened(filename string, file *os.File, opts *options) (c counts, members []result, err error) {
+	if opts.archive {
+		archive, _, err := countArchive(filename, file, file, opts)
+		if archive != nil {
+			return archive.total, archive.members, err
+		}
+		// not an archive, counted again from the start
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return counts{}, nil, err
+		}
+	}
+	if opts.walk.recursive {
+		binary, err := isBinaryFile(file, opts)
+		if err != nil {
+			return counts{}, nil, err
+		}
+		if binary {
+			return counts{}, nil, errBinary
+		}
+	}
+
+	if opts.decompress {
+		c, err = countCompressed(file, opts.countOptions(filename))
+	} else {
+		c, err = countOpenFile(file, opts.chunks, opts.countOptions(filename))
+	}
+	c.language = opts.languageOf(filename)
+	return c, nil, err
+}
+
+// isBinaryFile reports whether the start of file is binary, once
+// decompressed with -z since compressed data is binary itself. The position
+// of file is left unchanged.
+func isBinaryFile(file *os.File, opts *options) (bool, error) {
+	var start io.Reader = io.NewSectionReader(file, 0, binarySniffSize)
+	if opts.decompress {
+		var err error
+		start, err = decompress(io.NewSectionReader(file, 0, math.MaxInt64))
+		if err != nil {
+			return false, err
+		}
+	}
+	return isBinary(start, opts.count.Encoding)
+}
+
+// countStream counts a stream that cannot be split, such as standard input
+func countStream(r io.Reader, opts *options) (c counts, members []result, err error) {
+	if opts.archive {
+		archive, rest, err := countArchive("-", r, nil, opts)
+		if archive != nil {
+			return archive.total, archive.members, err
+		}
+		r = rest
+	}
+
+	if opts.decompress {
+		c, err = countCompressed(r, opts.countOptions(""))
+	} else {
+		c, err = countReader(r, opts.countOptions(""))
+	}
+	c.language = opts.languageOf("")
+	return c, nil, err
+}
+
+// inputError is the error of an input, printed like GNU wc prints it
+type inputError struct {
+	name string
+	err  error
+}
+
+func (e *inputError) Error() string {
+	name := e.name
+	if isStdin(name) {
+		name = "standard input"
+	}
+	return name + ": " + errorMessage(e.err)
+}
+
+func (e *inputError) Unwrap() error {
+	return e.err
+}
+
+// errorMessage returns the message of err the way the C library describes
+// it, such as "No such file or directory"
+func errorMessage(err error) string {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		msg := errno.Error()
+		return strings.ToUpper(msg[:1]) + msg[1:]
+	}
+	return err.Error()
+}
+
+// result is the outcome of counting one input
+type result struct {
+	name    string
+	counts  counts
+	err     error
+	skipped bool // the file was not counted because it is binary
+	// the files of an archive, with --archive
+	members []result
+	member  bool // a file of an archive, also counted in the row of the archive
+}
+
+func newResult(name string, c counts, members []result, err error) result {
+	if errors.Is(err, errBinary) {
+		return result{name: name, skipped: true}
+	}
+	return result{name: name, counts: c, err: err, members: members}
+}
+
+// printed reports whether the counts of the result are printed: GNU wc
+// prints the counts read before an error, unless the input could not even
+// be opened
+func (r result) printed() bool {
+	var pathErr *fs.PathError
+	return r.err == nil || !errors.As(r.err, &pathErr) || pathErr.Op != "open"
+}
+
+// countFiles counts the files of opts with a pool of workers and yields the
+// results in the order of the files. Workers run ahead of the consumer,
+// standard input is read by the consumer itself so it is only counted once
+// and in order.
+func countFiles(opts *options, stdin io.Reader) iter.Seq[result] {
+	files := opts.files
+	return func(yield func(result) bool) {
+		pending := make([]chan result, len(files))
+		for i := range pending {
+			// buffered so a worker never waits for the consumer
+			pending[i] = make(chan result, 1)
+		}
+
+		indexes := make(chan int)
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		for range max(opts.jobs, 1) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indexes {
+					c, members, err := countFile(files[i], nil, opts)
+					pending[i] <- newResult(files[i], c, members, err)
+				}
+			}()
+		}
+		go func() {
+			defer close(indexes)
+			for i, filename := range files {
+				if isStdin(filename) {
+					continue
+				}
+				select {
+				case indexes <- i:
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(done)
+			wg.Wait()
+		}()
+
+		for i, filename := range files {
+			var r result
+			if isStdin(filename) {
+				c, members, err := countFile(filename, stdin, opts)
+				r = newResult(filename, c, members, err)
+			} else {
+				r = <-pending[i]
+			}
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
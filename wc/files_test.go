@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountFilesKeepsArgumentOrder(t *testing.T) {
+	contents := make([]string, 50)
+	for i := range contents {
+		contents[i] = strings.Repeat(fmt.Sprintf("line %d\n", i), i*100)
+	}
+	paths := writeTestFiles(t, contents...)
+
+	i := 0
+	for r := range countFiles(&options{files: paths, count: defaultOptions, jobs: 8}, nil) {
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if r.name != paths[i] {
+			t.Fatalf("result %d is for %s, want %s", i, r.name, paths[i])
+		}
+		if want := countContent([]byte(contents[i])); r.counts != want {
+			t.Errorf("counts of %s = %+v, want %+v", r.name, r.counts, want)
+		}
+		i++
+	}
+	if i != len(paths) {
+		t.Errorf("got %d results, want %d", i, len(paths))
+	}
+}
+
+func TestCountFilesStdinInOrder(t *testing.T) {
+	paths := writeTestFiles(t, "a\n", "b b\n")
+	files := []string{paths[0], "-", paths[1], "-"}
+
+	var got []string
+	for r := range countFiles(&options{files: files, count: defaultOptions, jobs: 4}, strings.NewReader("x y z\n")) {
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		got = append(got, fmt.Sprintf("%s:%d", filepath.Base(r.name), r.counts.Words))
+	}
+
+	// standard input is consumed by the first "-"
+	want := []string{"file1.txt:1", "-:3", "file2.txt:2", "-:0"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestCountFilesStopsEarly(t *testing.T) {
+	contents := make([]string, 20)
+	for i := range contents {
+		contents[i] = "hello\n"
+	}
+	paths := writeTestFiles(t, contents...)
+
+	for range countFiles(&options{files: paths, count: defaultOptions, jobs: 4}, nil) {
+		// breaking must not leak or block the workers
+		break
+	}
+}
+
+func TestRunParallelMatchesSerial(t *testing.T) {
+	contents := make([]string, 30)
+	for i := range contents {
+		contents[i] = strings.Repeat("some words here\n", i+1)
+	}
+	paths := writeTestFiles(t, contents...)
+
+	serial := runOutput(t, append([]string{"-j", "1"}, paths...)...)
+	parallel := runOutput(t, append([]string{"-j", "0"}, paths...)...)
+	if serial != parallel {
+		t.Errorf("parallel output =\n%s\nwant\n%s", parallel, serial)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"wc/count"
+)
+
+// followed is a file counted by -f. The counts are running totals of
+// everything read under its name: when the file is rotated or truncated the
+// counts so far are kept and the new content is counted after them.
+type followed struct {
+	name    string
+	opts    count.Options
+	file    *os.File
+	counter *count.Counter
+	offset  int64  // bytes read from file
+	before  counts // counts of the content read before a rotation or truncation
+	// language of the lines of code, with --code
+	language string
+}
+
+// counts returns the running totals of the file
+func (f *followed) counts() counts {
+	c := f.before
+	c.add(countsOf(f.counter))
+	c.language = f.language
+	return c
+}
+
+// restart keeps the counts so far and counts the content of file from its
+// start
+func (f *followed) restart(file *os.File) {
+	f.before.add(countsOf(f.counter))
+	f.file = file
+	f.counter = count.NewCounter(f.opts)
+	f.offset = 0
+}
+
+// update counts the data appended since the last update and follows the
+// name when the file is rotated, it reports whether new data was counted
+func (f *followed) update() (bool, error) {
+	info, err := f.file.Stat()
+	if err != nil {
+		return false, &inputError{f.name, err}
+	}
+
+	changed := false
+	if info.Mode().IsRegular() && info.Size() < f.offset {
+		// truncated, the content starts again from the beginning
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return false, &inputError{f.name, err}
+		}
+		f.restart(f.file)
+	}
+	if err := f.read(&changed); err != nil {
+		return false, err
+	}
+
+	current, err := os.Stat(f.name)
+	if errors.Is(err, fs.ErrNotExist) {
+		// rotated away and not created again yet
+		return changed, nil
+	}
+	if err != nil {
+		return false, &inputError{f.name, err}
+	}
+	if os.SameFile(info, current) {
+		return changed, nil
+	}
+
+	file, err := os.Open(f.name)
+	if err != nil {
+		return false, &inputError{f.name, err}
+	}
+	if err := f.file.Close(); err != nil {
+		return false, &inputError{f.name, err}
+	}
+	f.restart(file)
+	if err := f.read(&changed); err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+// read counts the file until its current end
+func (f *followed) read(changed *bool) error {
+	n, err := f.counter.ReadFrom(f.file)
+	f.offset += n
+	if n > 0 {
+		*changed = true
+	}
+	if err != nil {
+		return &inputError{f.name, err}
+	}
+	return nil
+}
+
+// follow counts the files of opts, then counts the data appended to them on
+// every tick and prints the running totals when they changed, until ctx is
+// done. A file that fails is reported on stderr and no longer followed, the
+// others still are and errReported is returned at the end.
+func follow(ctx context.Context, opts *options, w, stderr io.Writer, ticks <-chan time.Time) (err error) {
+	files := make([]*followed, 0, len(opts.files))
+	defer func() {
+		for _, f := range files {
+			closeFile(f.file, &err)
+		}
+	}()
+	failed := false
+	report := func(err error) {
+		failed = true
+		fmt.Fprintf(stderr, "wc: %v\n", err)
+	}
+	for _, name := range opts.files {
+		if isStdin(name) {
+			return errors.New("cannot follow standard input")
+		}
+		file, err := os.Open(name)
+		if err != nil {
+			report(&inputError{name, err})
+			continue
+		}
+		f := &followed{
+			name:     name,
+			opts:     opts.countOptions(name),
+			file:     file,
+			language: opts.languageOf(name),
+		}
+		f.counter = count.NewCounter(f.opts)
+		var changed bool
+		if err := f.read(&changed); err != nil {
+			report(err)
+			_ = file.Close()
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return errReported
+	}
+
+	if err := printFollowed(opts, w, files); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if failed {
+				return errReported
+			}
+			return nil
+		case <-ticks:
+		}
+
+		changed := false
+		followed := files[:0]
+		for _, f := range files {
+			fileChanged, err := f.update()
+			if err != nil {
+				// its row is no longer printed
+				report(err)
+				_ = f.file.Close()
+				changed = true
+				continue
+			}
+			followed = append(followed, f)
+			changed = changed || fileChanged
+		}
+		files = followed
+		if len(files) == 0 {
+			return errReported
+		}
+		if changed {
+			if err := printFollowed(opts, w, files); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// printFollowed prints the running totals of every file, and their total
+func printFollowed(opts *options, w io.Writer, files []*followed) error {
+	// the width only comes from the files still followed
+	followedOpts := *opts
+	followedOpts.files = make([]string, len(files))
+	for i, f := range files {
+		followedOpts.files[i] = f.name
+	}
+	rep := newReporter(opts, w, numberWidth(&followedOpts, nil))
+	var total counts
+	languages := make(languageTotals)
+	for _, f := range files {
+		c := f.counts()
+		total.add(c)
+		languages.add(c)
+		rep.file(result{name: f.name, counts: c})
+	}
+	if opts.total.print(len(files)) {
+		if opts.total != totalOnly {
+			languages.report(rep)
+		}
+		rep.total(total)
+	}
+	return rep.close()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendFile(t *testing.T, name, content string) {
+	t.Helper()
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, name, "a b\n")
+	opts, err := parseArgs([]string{"-f", name}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time)
+	var stdout bytes.Buffer
+	done := make(chan error)
+	go func() { done <- follow(ctx, opts, &stdout, io.Discard, ticks) }()
+
+	// the second tick is only received once the first one was handled
+	tick := func() {
+		ticks <- time.Now()
+		ticks <- time.Now()
+	}
+	tick() // the files were counted once a tick is received
+	appendFile(t, name, "c d\n")
+	tick()
+	tick() // nothing changed, nothing is printed
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, name+".1", "end of the old file\n")
+	appendFile(t, name, "new\n")
+	tick()
+	if err := os.Truncate(name, 0); err != nil {
+		t.Fatal(err)
+	}
+	tick()
+	appendFile(t, name, "x\n")
+	tick()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []string{"1 2 4", "2 4 8", "4 10 32", "5 11 34"}
+	var want strings.Builder
+	for _, row := range rows {
+		fmt.Fprintf(&want, "%s %s\n", row, name)
+	}
+	if stdout.String() != want.String() {
+		t.Errorf("output = %q, want %q", stdout.String(), want.String())
+	}
+}
+
+func TestFollowTotals(t *testing.T) {
+	paths := writeTestFiles(t, "one\n", "two words\n")
+	opts, err := parseArgs([]string{"-f", "-l", paths[0], paths[1]}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time)
+	var stdout bytes.Buffer
+	done := make(chan error)
+	go func() { done <- follow(ctx, opts, &stdout, io.Discard, ticks) }()
+
+	ticks <- time.Now()
+	appendFile(t, paths[1], "three\n")
+	ticks <- time.Now()
+	ticks <- time.Now()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf(" 1 %s\n 1 %s\n 2 total\n 1 %s\n 2 %s\n 3 total\n", paths[0], paths[1], paths[0], paths[1])
+	if stdout.String() != want {
+		t.Errorf("output = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestFollowFailingFiles(t *testing.T) {
+	paths := writeTestFiles(t, "a b\n", "c\n")
+	missing := filepath.Join(filepath.Dir(paths[0]), "missing.log")
+	opts, err := parseArgs([]string{"-f", "-l", paths[0], missing, paths[1]}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time)
+	var stdout, stderr bytes.Buffer
+	done := make(chan error)
+	go func() { done <- follow(ctx, opts, &stdout, &stderr, ticks) }()
+
+	ticks <- time.Now()
+	// the file is replaced by a directory, which cannot be read
+	if err := os.Rename(paths[0], paths[0]+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(paths[0], 0o700); err != nil {
+		t.Fatal(err)
+	}
+	ticks <- time.Now()
+	ticks <- time.Now()
+	appendFile(t, paths[1], "d\n")
+	ticks <- time.Now()
+	ticks <- time.Now()
+	cancel()
+	if err := <-done; !errors.Is(err, errReported) {
+		t.Errorf("follow() returned %v, want errReported", err)
+	}
+
+	want := fmt.Sprintf("1 %s\n1 %s\n2 total\n1 %s\n2 %s\n", paths[0], paths[1], paths[1], paths[1])
+	if stdout.String() != want {
+		t.Errorf("output = %q, want %q", stdout.String(), want)
+	}
+	wantErrors := fmt.Sprintf("wc: %s: No such file or directory\nwc: %s: Is a directory\n", missing, paths[0])
+	if stderr.String() != wantErrors {
+		t.Errorf("errors = %q, want %q", stderr.String(), wantErrors)
+	}
+}
+
+func TestFollowErrors(t *testing.T) {
+	var stdout bytes.Buffer
+	for _, args := range [][]string{{"-f"}, {"-f", "missing.txt"}, {"-f", "-z", "lorum.txt"}, {"-f", "--interval", "0s", "lorum.txt"}} {
+		if err := run(args, strings.NewReader(""), &stdout, io.Discard); err == nil {
+			t.Errorf("run(%v) succeeded, want an error", args)
+		}
+	}
+}
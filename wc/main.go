@@ -1,89 +1,519 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strings"
+	"runtime"
+	"slices"
+	"time"
+
+	"wc/count"
 )
 
-func closeFile(file *os.File) {
-	if err := file.Close(); err != nil {
-		fmt.Println("Error closing file: ", file.Name(), err)
-		os.Exit(1)
+// closeFile closes a file that was read, the error closing it is kept in
+// err unless an error happened before
+func closeFile(file *os.File, err *error) {
+	if closeErr := file.Close(); closeErr != nil && *err == nil {
+		*err = closeErr
 	}
 }
 
+// errReported is returned by run when the errors were already printed
+var errReported = errors.New("errors were reported")
+
 func countBytes(content []byte) int {
-	return len(content)
+	return int(countContent(content).Bytes)
 }
 
 func countLines(content []byte) int {
-	return strings.Count(string(content), "\n")
+	return int(countContent(content).Lines)
 }
 
 func countWords(content []byte) int {
-	return len(strings.Fields(string(content)))
+	return int(countContent(content).Words)
 }
 
 func countCharacters(content []byte) int {
-	return len(string(content))
+	return int(countContent(content).Chars)
 }
 
 func main() {
-	if err := run(); err != nil {
-		fmt.Println(err)
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errReported):
+		os.Exit(1)
+	default:
+		fmt.Fprintf(os.Stderr, "wc: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
+// options holds the parsed command line
+type options struct {
+	bytes bool
+	lines bool
+	words bool
+	chars bool
+	// display width of the longest line, optionally with its line number
+	maxLineLength bool
+	longestLine   bool
+	eolReport     bool        // print the line endings of each file
+	code          bool        // print the code, comment and blank lines of each file
+	stats         bool        // print the line length distribution of each file
+	patterns      stringList  // regular expressions whose matches are counted
+	printf        string      // template of the rows
+	template      template    // the parsed --printf template
+	cacheFile     string      // file of the counts of the previous run
+	noCache       bool        // ignore --cache
+	cache         *countCache // the counts of the previous run, with --cache
+	save          string      // file the counts are saved to
+	diff          string      // snapshot file the counts are compared with
+	before        *snapshot   // the snapshot of --diff
+	count         count.Options
+	total         totalMode
+	format        outputFormat
+	jobs          int // number of files counted concurrently
+	chunks        int // number of byte ranges of a file counted concurrently
+	walk          walkOptions
+	decompress    bool // count the decompressed content of compressed inputs
+	archive       bool // count the files of tar and zip archives
+	// number of most frequent words printed instead of the counts
+	top         int
+	frequencies count.FrequencyOptions
+	stopWords   string // file of words not counted by --top
+	// keep counting the data appended to the files, checked every interval
+	follow   bool
+	interval time.Duration
+	files    []string
+}
+
+func parseArgs(args []string, stderr io.Writer) (*options, error) {
 	// process arguments
 	// -c count bytes
 	// -l count lines
 	// -w count words
 	// -m count characters
+	// -L print the maximum display width of a line
+	// --longest-line print the line number of the longest line
+	// --invalid=count|skip how -m counts bytes that are not valid UTF-8
+	// --graphemes -m counts grapheme clusters instead of code points
+	// --words=posix|unicode|uax29 how -w splits words
+	// --eol=lf|crlf|cr|any which line terminators -l counts
+	// --encoding=auto|utf-8|utf-16le|utf-16be|latin1 character encoding of the inputs, auto detects byte order marks
+	// --eol-report print the line terminators, the line ending style and whether the final newline is missing
+	// -e PATTERN count the matches of the regular expression and the lines containing one, repeatable
+	// --code print the code, comment and blank lines and the language of each file, with a total per language
+	// --stats print the minimum, median, 90th and 99th percentile, maximum and mean line length and a histogram
+	// --total=auto|always|only|never when to print the total row
+	// --format=text|json|csv|tsv output format
+	// --printf TEMPLATE print every row with TEMPLATE, such as '{lines:8}\t{path}\n'
+	// -j N count up to N files concurrently, 0 uses every CPU
+	// -r count the files of directories recursively, binary files are skipped
+	// --include=GLOB only count the files matching GLOB in directories, repeatable
+	// --exclude=GLOB skip the files and directories matching GLOB, repeatable
+	// --gitignore skip the files ignored by .gitignore files in directories
+	// -z --decompress count the content of gzip, bzip2 and zlib inputs
+	// --archive count each file of tar and zip archives, with a total per archive
+	// --chunks N split each large file into N byte ranges counted concurrently, 0 uses every CPU
+	// --top N print the N most frequent words of all inputs instead of the counts
+	// --fold count words case insensitively with --top
+	// --min-length N ignore words of fewer than N characters with --top
+	// --stop-words FILE ignore the words listed in FILE with --top
+	// --save FILE save the counts of every file and the total to FILE
+	// --diff FILE print how the counts differ from those saved to FILE
+	// --cache FILE only count the files that changed since the previous run with FILE
+	// --no-cache count every file, even with --cache
+	// -f keep counting the data appended to the files and print running totals
+	// --interval D how often -f checks the files for new data
+
+	opts := &options{total: totalAuto, format: formatText, count: count.Options{Invalid: count.InvalidCount, Words: count.WordsUnicode, EOL: count.EOLLF, Encoding: count.EncodingUTF8}}
+	fs := flag.NewFlagSet("wc", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&opts.bytes, "c", false, "count bytes")
+	fs.BoolVar(&opts.lines, "l", false, "count lines")
+	fs.BoolVar(&opts.words, "w", false, "count words")
+	fs.BoolVar(&opts.chars, "m", false, "count characters")
+	fs.BoolVar(&opts.maxLineLength, "L", false, "print the maximum display width")
+	fs.BoolVar(&opts.longestLine, "longest-line", false, "print the line number of the longest line, implies -L")
+	fs.Var(&opts.count.Invalid, "invalid", "how -m counts bytes that are not valid UTF-8: count, skip")
+	fs.BoolVar(&opts.count.Graphemes, "graphemes", false, "-m counts grapheme clusters instead of code points")
+	fs.Var(&opts.count.EOL, "eol", "line terminators counted by -l: lf, crlf, cr, any")
+	fs.Var(&opts.count.Encoding, "encoding", "character encoding of the inputs: auto (detected by the byte order mark, else utf-8), utf-8, utf-16le, utf-16be, latin1; -c still counts the raw bytes")
+	fs.BoolVar(&opts.eolReport, "eol-report", false, "print the number of each line terminator, the line ending style and whether the final newline is missing")
+	fs.Var(&opts.patterns, "e", "print the number of matches of the regular expression `PATTERN` and of the lines containing one, can be repeated")
+	fs.BoolVar(&opts.code, "code", false, "print the code, comment and blank lines and the language detected by the extension of each file, with a total per language")
+	fs.BoolVar(&opts.stats, "stats", false, "print the minimum, median, 90th and 99th percentile, maximum and mean line length in bytes of each file, and a histogram")
+	fs.Var(&opts.count.Words, "words", "how -w splits words: posix (ASCII whitespace), unicode (Unicode whitespace), uax29 (word boundaries)")
+	fs.Var(&opts.total, "total", "when to print a line with total counts: auto, always, only, never")
+	fs.Var(&opts.format, "format", "output format: text, json, csv, tsv")
+	fs.StringVar(&opts.printf, "printf", "", "print every row with `TEMPLATE`: {field} or {field:[<>^]width} is a count, a text or the path, \\n, \\t, \\r, \\\\, \\{ and \\} are escapes")
+	fs.BoolVar(&opts.walk.recursive, "r", false, "count the files of directories recursively, skipping binary files")
+	fs.Var(&opts.walk.include, "include", "with -r, only count files matching the glob, can be repeated")
+	fs.Var(&opts.walk.exclude, "exclude", "with -r, skip files and directories matching the glob, can be repeated")
+	fs.BoolVar(&opts.walk.gitignore, "gitignore", false, "with -r, skip files ignored by .gitignore files")
+	fs.BoolVar(&opts.decompress, "z", false, "count the decompressed content of gzip, bzip2 and zlib inputs")
+	fs.BoolVar(&opts.decompress, "decompress", false, "same as -z")
+	fs.BoolVar(&opts.archive, "archive", false, "print a row for each file of tar archives, compressed or not, and zip archives, named ARCHIVE:PATH, and a total row per archive")
+	fs.IntVar(&opts.jobs, "j", 1, "number of files counted concurrently, 0 uses every CPU")
+	fs.IntVar(&opts.chunks, "chunks", 1, "number of byte ranges a large file is split into and counted concurrently, 0 uses every CPU")
+	fs.IntVar(&opts.top, "top", 0, "print the `N` most frequent words of all inputs instead of the counts")
+	fs.BoolVar(&opts.frequencies.Fold, "fold", false, "with --top, count words case insensitively")
+	fs.IntVar(&opts.frequencies.MinLength, "min-length", 0, "with --top, ignore words of fewer than `N` characters")
+	fs.StringVar(&opts.stopWords, "stop-words", "", "with --top, ignore the words listed one per line in `FILE`")
+	fs.StringVar(&opts.save, "save", "", "save the counts of every file and the total to the snapshot `FILE`")
+	fs.StringVar(&opts.diff, "diff", "", "print the difference of the counts with the snapshot `FILE` saved by --save: the files added, removed and changed and the difference of the total")
+	fs.StringVar(&opts.cacheFile, "cache", "", "keep the counts of the files in the cache `FILE`, a later run only counts the files whose path, size, modification time or inode changed")
+	fs.BoolVar(&opts.noCache, "no-cache", false, "count every file, ignoring --cache")
+	fs.BoolVar(&opts.follow, "f", false, "keep counting the data appended to the files and print running totals when they change")
+	fs.DurationVar(&opts.interval, "interval", time.Second, "with -f, how often the files are checked for new data")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		// the flag package printed the error and the usage
+		return nil, fmt.Errorf("%w: %w", errReported, err)
+	}
+	switch {
+	case opts.jobs < 0:
+		return nil, fmt.Errorf("invalid number of jobs %d", opts.jobs)
+	case opts.jobs == 0:
+		opts.jobs = runtime.NumCPU()
+	}
+	switch {
+	case opts.chunks < 0:
+		return nil, fmt.Errorf("invalid number of chunks %d", opts.chunks)
+	case opts.chunks == 0:
+		opts.chunks = runtime.NumCPU()
+	}
+	switch {
+	case opts.follow && opts.decompress:
+		return nil, errors.New("-f cannot count compressed files")
+	case opts.follow && opts.archive:
+		return nil, errors.New("-f cannot count archives")
+	case opts.follow && (opts.save != "" || opts.diff != ""):
+		return nil, errors.New("-f cannot save nor compare snapshots")
+	case opts.top > 0 && opts.diff != "":
+		return nil, errors.New("--top cannot compare snapshots")
+	case opts.printf != "" && (opts.top > 0 || opts.format != formatText):
+		return nil, errors.New("--printf only prints rows of counts as text")
+	case opts.interval <= 0:
+		return nil, fmt.Errorf("invalid interval %s", opts.interval)
+	}
+	opts.count.Stats = opts.stats
+	if len(opts.patterns) > 0 {
+		pattern, err := compilePatterns(opts.patterns)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		opts.count.Pattern = pattern
+	}
+	switch {
+	case opts.top < 0:
+		return nil, fmt.Errorf("invalid number of words %d", opts.top)
+	case opts.frequencies.MinLength < 0:
+		return nil, fmt.Errorf("invalid minimum word length %d", opts.frequencies.MinLength)
+	case opts.top > 0:
+		if opts.stopWords != "" {
+			words, err := readStopWords(opts.stopWords, opts.frequencies.Fold)
+			if err != nil {
+				return nil, fmt.Errorf("error reading stop words: %w", err)
+			}
+			opts.frequencies.StopWords = words
+		}
+		opts.count.Frequencies = &opts.frequencies
+	}
+
+	if opts.diff != "" {
+		before, err := readSnapshot(opts.diff, opts.countColumns())
+		if err != nil {
+			return nil, fmt.Errorf("error reading snapshot: %w", err)
+		}
+		opts.before = before
+	}
 
-	bytesFlag := flag.Bool("c", false, "count bytes")
-	linesFlag := flag.Bool("l", false, "count lines")
-	wordsFlag := flag.Bool("w", false, "count words")
-	charsFlag := flag.Bool("m", false, "count characters")
+	if opts.printf != "" {
+		known := countedColumns
+		if opts.before != nil {
+			// the counts of the template are the differences
+			known = nil
+		}
+		tmpl, err := parseTemplate(opts.printf, opts.columns(), known)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --printf: %w", err)
+		}
+		opts.template = tmpl
+	}
+
+	opts.skipCounts()
+
+	// the word frequencies, line length statistics and archive members
+	// are not cached, nor the files followed
+	if opts.cacheFile != "" && !opts.noCache && opts.top == 0 && !opts.stats && !opts.archive && !opts.follow {
+		cache, err := openCache(opts.cacheFile, opts.cacheOptions())
+		if err != nil {
+			return nil, fmt.Errorf("error reading cache: %w", err)
+		}
+		opts.cache = cache
+	}
+
+	opts.files = fs.Args()
+	if len(opts.files) == 0 {
+		// without file arguments standard input is counted and no name is printed
+		opts.files = []string{""}
+	}
+	for i, filename := range opts.files {
+		if !isStdin(filename) {
+			opts.files[i] = filepath.Clean(filename)
+		}
+	}
+	if _, err := newWalker(&opts.walk); err != nil {
+		return nil, fmt.Errorf("invalid glob: %w", err)
+	}
+
+	return opts, nil
+}
+
+// column is a count that can be selected on the command line, or a text
+// when label is set
+type column struct {
+	name   string
+	value  func(c counts) int64
+	label  func(c counts) string
+	number bool // the label is a number, not quoted in JSON
+}
+
+var (
+	linesColumn = column{"lines", func(c counts) int64 { return c.Lines }, nil, false}
+	wordsColumn = column{"words", func(c counts) int64 { return c.Words }, nil, false}
+	charsColumn = column{"chars", func(c counts) int64 { return c.Chars }, nil, false}
+	bytesColumn = column{"bytes", func(c counts) int64 { return c.Bytes }, nil, false}
+
+	maxLineLengthColumn = column{"max_line_length", func(c counts) int64 { return c.MaxLineLength }, nil, false}
+	longestLineColumn   = column{"longest_line", func(c counts) int64 { return c.LongestLine }, nil, false}
+	compressedColumn    = column{"compressed", func(c counts) int64 { return c.compressed }, nil, false}
 
-	flag.Parse()
+	eolColumns = []column{
+		{"lf", func(c counts) int64 { return c.LF }, nil, false},
+		{"crlf", func(c counts) int64 { return c.CRLF }, nil, false},
+		{"cr", func(c counts) int64 { return c.CR }, nil, false},
+		{"no_final_newline", func(c counts) int64 { return c.Unterminated }, nil, false},
+		{"eol", nil, func(c counts) string { return c.Style() }, false},
+	}
+
+	// countedColumns are counted whatever the options, so a --printf
+	// template can print them without selecting them
+	countedColumns = append([]column{linesColumn, wordsColumn, charsColumn, bytesColumn, maxLineLengthColumn, longestLineColumn}, eolColumns...)
+)
 
-	// parse file from the last argument
-	filename := flag.Args()[len(flag.Args())-1]
-	filename = filepath.Clean(filename)
-	file, err := os.Open(filename)
+// columns returns the printed columns: the selected counts, or how they
+// differ from the snapshot with --diff
+func (o *options) columns() []column {
+	if o.before != nil {
+		return diffColumns(o.countColumns())
+	}
+	return o.countColumns()
+}
+
+// skipCounts lets the counter skip the counts that are neither printed nor
+// saved: the width of the lines, and every count but the lines and the bytes
+// when only they are needed, which is much faster on large files
+func (o *options) skipCounts() {
+	needed := o.countColumns()
+	if o.format != formatText {
+		needed = o.fieldColumns()
+	}
+	if o.template != nil {
+		needed = append(needed, countedColumns...)
+	}
+	if o.save != "" {
+		needed = append(needed, o.snapshotColumns()...)
+	}
+
+	o.count.LinesOnly = true
+	o.count.NoWidth = true
+	for _, col := range needed {
+		switch col.name {
+		case linesColumn.name, bytesColumn.name, compressedColumn.name:
+		case maxLineLengthColumn.name, longestLineColumn.name:
+			o.count.LinesOnly = false
+			o.count.NoWidth = false
+		default:
+			o.count.LinesOnly = false
+		}
+	}
+}
+
+// fieldColumns returns the columns of the JSON, CSV and TSV formats: the
+// lines, words, characters and bytes whatever the flags select, so the
+// fields do not depend on them, followed by the other selected columns
+func (o *options) fieldColumns() []column {
+	columns := []column{linesColumn, wordsColumn, charsColumn, bytesColumn}
+	for _, col := range o.countColumns() {
+		if !slices.ContainsFunc(columns, func(c column) bool { return c.name == col.name }) {
+			columns = append(columns, col)
+		}
+	}
+	if o.before != nil {
+		return diffColumns(columns)
+	}
+	return columns
+}
+
+// histograms reports whether the histogram of the line lengths follows the
+// counts, the histograms are not compared with --diff
+func (o *options) histograms() bool {
+	return o.stats && o.before == nil
+}
+
+// countColumns returns the selected counts in the canonical order used by
+// GNU wc: lines, words, characters, bytes, maximum line length. Without any
+// flag lines, words and bytes are printed. The compressed size follows when
+// decompressing, then the matches of the patterns, the line endings, the
+// lines of code and the line length statistics.
+func (o *options) countColumns() []column {
+	var columns []column
+	if !o.lines && !o.words && !o.chars && !o.bytes && !o.maxLineLength && !o.longestLine {
+		columns = []column{linesColumn, wordsColumn, bytesColumn}
+	}
+
+	if o.lines {
+		columns = append(columns, linesColumn)
+	}
+	if o.words {
+		columns = append(columns, wordsColumn)
+	}
+	if o.chars {
+		columns = append(columns, charsColumn)
+	}
+	if o.bytes {
+		columns = append(columns, bytesColumn)
+	}
+	if o.maxLineLength || o.longestLine {
+		columns = append(columns, maxLineLengthColumn)
+	}
+	if o.longestLine {
+		columns = append(columns, longestLineColumn)
+	}
+	if o.decompress {
+		columns = append(columns, compressedColumn)
+	}
+	if len(o.patterns) > 0 {
+		columns = append(columns, matchColumns...)
+	}
+	if o.eolReport {
+		columns = append(columns, eolColumns...)
+	}
+	if o.code {
+		columns = append(columns, codeColumns...)
+	}
+	if o.stats {
+		columns = append(columns, statsColumns...)
+	}
+	return columns
+}
+
+// values returns the selected counts of c in the order they are printed,
+// and the texts that follow them
+func (o *options) values(c counts) ([]int64, []string) {
+	var values []int64
+	var labels []string
+	for _, col := range o.columns() {
+		if col.label != nil {
+			labels = append(labels, col.label(c))
+		} else {
+			values = append(values, col.value(c))
+		}
+	}
+	return values, labels
+}
+
+// run counts the inputs given by args like GNU wc: an input that fails is
+// reported on stderr and left out of the total, the other inputs are still
+// counted and errReported is returned at the end
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	opts, err := parseArgs(args, stderr)
 	if err != nil {
-		fmt.Println("Error opening file: ", err)
 		return err
 	}
-	defer closeFile(file)
 
-	// read file
-	content, err := io.ReadAll(file)
+	// directories that cannot be read are reported, the others are counted
+	var failed bool
+	opts.files, failed, err = expandFiles(opts.files, &opts.walk, stderr)
 	if err != nil {
-		fmt.Println("Error reading file: ", err)
-
 		return err
 	}
 
-	// process flags
-	switch {
-	case *bytesFlag:
-		fmt.Println(countBytes(content), filename)
-	case *linesFlag:
-		fmt.Println(countLines(content), filename)
-	case *wordsFlag:
-		fmt.Println(countWords(content), filename)
-	case *charsFlag:
-		fmt.Println(countCharacters(content), filename)
-	default:
-		fmt.Println(countBytes(content), countLines(content), countWords(content), filename)
+	if opts.follow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+		return follow(ctx, opts, stdout, stderr, ticker.C)
 	}
 
+	var rep reporter = newReporter(opts, stdout, numberWidth(opts, stdin))
+	if opts.before != nil {
+		rep = newDiffReporter(rep, opts)
+	}
+	var saved *snapshot
+	if opts.save != "" {
+		saved = newSnapshot(opts.snapshotColumns())
+	}
+	var total counts
+	languages := make(languageTotals)
+	for r := range countFiles(opts, stdin) {
+		if r.skipped {
+			continue
+		}
+		for _, member := range r.members {
+			languages.add(member.counts)
+			saved.add(member)
+			rep.file(member)
+		}
+		if r.err != nil {
+			failed = true
+			fmt.Fprintf(stderr, "wc: %v\n", &inputError{r.name, r.err})
+		} else {
+			total.add(r.counts)
+			if r.members == nil {
+				languages.add(r.counts)
+			}
+			saved.add(r)
+		}
+		rep.file(r)
+	}
+
+	if opts.total.print(len(opts.files)) {
+		// --total=only prints the grand total alone
+		if opts.total != totalOnly {
+			languages.report(rep)
+		}
+		rep.total(total)
+	}
+
+	if err := rep.close(); err != nil {
+		return err
+	}
+	if saved != nil {
+		if err := saved.write(opts.save, total); err != nil {
+			return fmt.Errorf("error saving snapshot: %w", err)
+		}
+	}
+	if opts.cache != nil {
+		if err := opts.cache.save(); err != nil {
+			return fmt.Errorf("error saving cache: %w", err)
+		}
+	}
+	if failed {
+		return errReported
+	}
 	return nil
 }
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"wc/count"
 )
 
 func TestCountBytes(t *testing.T) {
@@ -88,6 +95,7 @@ func TestCountCharacters(t *testing.T) {
 		{"single char", []byte("a"), 1},
 		{"hello world", []byte("hello world"), 11},
 		{"with newlines", []byte("hello\nworld"), 11},
+		{"unicode", []byte("café"), 4},
 	}
 
 	for _, tt := range tests {
@@ -209,3 +217,293 @@ func TestLorumFile(t *testing.T) {
 		t.Errorf("Expected 445 characters, got %d", chars)
 	}
 }
+
+// defaultOptions are the counting options used when no flag is given
+var defaultOptions = count.Options{Invalid: count.InvalidCount}
+
+// writeTestFiles creates files with the given contents in a temporary
+// directory and returns their paths in the same order
+func writeTestFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, content := range contents {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("file%d.txt", i+1))
+		if err := os.WriteFile(paths[i], []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func runOutput(t *testing.T, args ...string) string {
+	t.Helper()
+	return runWithStdin(t, "", args...)
+}
+
+func runWithStdin(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+	var stdout bytes.Buffer
+	if err := run(args, strings.NewReader(stdin), &stdout, io.Discard); err != nil {
+		t.Fatalf("run(%v) returned error: %v", args, err)
+	}
+	return stdout.String()
+}
+
+func TestRunMultipleFiles(t *testing.T) {
+	paths := writeTestFiles(t, "hello world\n", strings.Repeat("a b c\n", 20))
+
+	got := runOutput(t, paths...)
+	// 132 bytes in total, so every column is 3 wide
+	want := fmt.Sprintf("  1   2  12 %s\n 20  60 120 %s\n 21  62 132 total\n", paths[0], paths[1])
+	if got != want {
+		t.Errorf("run() output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRunTotalModes(t *testing.T) {
+	paths := writeTestFiles(t, "hello world\n", "one two three\n")
+
+	tests := []struct {
+		mode string
+		args []string
+		want string
+	}{
+		{"auto", paths, fmt.Sprintf(" 1  2 12 %s\n 1  3 14 %s\n 2  5 26 total\n", paths[0], paths[1])},
+		{"auto", paths[:1], fmt.Sprintf(" 1  2 12 %s\n", paths[0])},
+		{"always", paths[:1], fmt.Sprintf(" 1  2 12 %s\n 1  2 12 total\n", paths[0])},
+		{"never", paths, fmt.Sprintf(" 1  2 12 %s\n 1  3 14 %s\n", paths[0], paths[1])},
+		{"only", paths, "2 5 26\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got := runOutput(t, append([]string{"--total=" + tt.mode}, tt.args...)...)
+			if got != tt.want {
+				t.Errorf("run() output =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunInvalidTotalMode(t *testing.T) {
+	var stdout bytes.Buffer
+	if err := run([]string{"--total=sometimes", "lorum.txt"}, strings.NewReader(""), &stdout, io.Discard); err == nil {
+		t.Error("Expected an error for an invalid --total value")
+	}
+}
+
+func TestRunUnknownFlag(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"--bogus", "lorum.txt"}, strings.NewReader(""), &stdout, &stderr)
+	if !errors.Is(err, errReported) {
+		t.Errorf("run() returned %v, want errReported", err)
+	}
+	// the error and the usage are written to the stderr of run
+	if got := stderr.String(); !strings.HasPrefix(got, "flag provided but not defined: -bogus\nUsage of wc:\n") {
+		t.Errorf("run() stderr = %q, want the error and the usage", got)
+	}
+	if stdout.Len() > 0 {
+		t.Errorf("run() output = %q, want none", stdout.String())
+	}
+}
+
+func TestRunSingleCountIsNotPadded(t *testing.T) {
+	paths := writeTestFiles(t, strings.Repeat("hello world\n", 100))
+
+	got := runOutput(t, "-l", paths[0])
+	if want := "100 " + paths[0] + "\n"; got != want {
+		t.Errorf("run() output = %q, want %q", got, want)
+	}
+}
+
+func TestRunStdin(t *testing.T) {
+	paths := writeTestFiles(t, "hello world\n")
+	stdin := "one two three\nfour\n"
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no arguments", nil, "      2       4      19\n"},
+		{"single count is not padded", []string{"-w"}, "4\n"},
+		{"dash", []string{"-"}, "      2       4      19 -\n"},
+		{"mixed with files", []string{paths[0], "-"}, fmt.Sprintf(
+			"      1       2      12 %s\n      2       4      19 -\n      3       6      31 total\n", paths[0])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runWithStdin(t, stdin, tt.args...)
+			if got != tt.want {
+				t.Errorf("run() output =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCombinedFlags(t *testing.T) {
+	stdin := "hello world\n"
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"lines and words", []string{"-l", "-w"}, "      1       2\n"},
+		{"flag order does not matter", []string{"-c", "-l"}, "      1      12\n"},
+		{"all counts", []string{"-c", "-m", "-w", "-l"}, "      1       2      12      12\n"},
+		{"default", nil, "      1       2      12\n"},
+		{"max line length last", []string{"-L", "-l"}, "      1      11\n"},
+		{"longest line number", []string{"--longest-line"}, "     11       1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runWithStdin(t, stdin, tt.args...)
+			if got != tt.want {
+				t.Errorf("run() output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunMissingFile(t *testing.T) {
+	paths := writeTestFiles(t, "hello\n", "two words\n")
+	missing := filepath.Join(filepath.Dir(paths[0]), "missing.txt")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"-j", "2", paths[0], missing, paths[1]}, strings.NewReader(""), &stdout, &stderr)
+	if !errors.Is(err, errReported) {
+		t.Errorf("run() error = %v, want %v", err, errReported)
+	}
+	// the other files are counted and only they are in the total
+	want := fmt.Sprintf(" 1  1  6 %s\n 1  2 10 %s\n 2  3 16 total\n", paths[0], paths[1])
+	if stdout.String() != want {
+		t.Errorf("run() output = %q, want %q", stdout.String(), want)
+	}
+	if want := fmt.Sprintf("wc: %s: No such file or directory\n", missing); stderr.String() != want {
+		t.Errorf("run() errors = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestRunDirectory(t *testing.T) {
+	paths := writeTestFiles(t, "hello\n")
+	dir := filepath.Dir(paths[0])
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{dir, paths[0]}, strings.NewReader(""), &stdout, &stderr)
+	if !errors.Is(err, errReported) {
+		t.Errorf("run() error = %v, want %v", err, errReported)
+	}
+	// a file that was opened is printed with the counts read before the error
+	want := fmt.Sprintf("      0       0       0 %s\n      1       1       6 %s\n      1       1       6 total\n", dir, paths[0])
+	if stdout.String() != want {
+		t.Errorf("run() output = %q, want %q", stdout.String(), want)
+	}
+	if want := fmt.Sprintf("wc: %s: Is a directory\n", dir); stderr.String() != want {
+		t.Errorf("run() errors = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestRunWordRules(t *testing.T) {
+	input := "Hello, world! 日本語のテキスト a\u00a0b\n"
+	tests := map[string]string{
+		"posix":   "4\n",
+		"unicode": "5\n",
+		"uax29":   "9\n",
+	}
+
+	for rule, want := range tests {
+		t.Run(rule, func(t *testing.T) {
+			if got := runWithStdin(t, input, "-w", "--total=only", "--words="+rule); got != want {
+				t.Errorf("output = %q, want %q", got, want)
+			}
+		})
+	}
+
+	var stdout bytes.Buffer
+	if err := run([]string{"--words=icu", "lorum.txt"}, strings.NewReader(""), &stdout, io.Discard); err == nil {
+		t.Error("Expected an error for an invalid --words value")
+	}
+}
+
+func TestRunEOL(t *testing.T) {
+	paths := writeTestFiles(t, "a\r\nb\r\nc", "a\nb\r\nc\r")
+
+	if got, want := runOutput(t, "-l", "--eol=crlf", "--total=never", paths[0]), "2 "+paths[0]+"\n"; got != want {
+		t.Errorf("--eol=crlf output = %q, want %q", got, want)
+	}
+	want := fmt.Sprintf(" 2  0  2  0  1 crlf %s\n 2  1  1  1  0 mixed %s\n 4  1  3  1  1 mixed total\n", paths[0], paths[1])
+	if got := runOutput(t, "-l", "--eol-report", paths[0], paths[1]); got != want {
+		t.Errorf("--eol-report output = %q, want %q", got, want)
+	}
+	want = `{"files":[` + "\n" + `{"path":` + fmt.Sprintf("%q", paths[1]) +
+		`,"lines":3,"words":3,"chars":7,"bytes":7,"lf":1,"crlf":1,"cr":1,"no_final_newline":0,"eol":"mixed"}` + "\n]}\n"
+	if got := runOutput(t, "-l", "--eol=any", "--eol-report", "--format=json", paths[1]); got != want {
+		t.Errorf("--format=json output = %q, want %q", got, want)
+	}
+
+	var stdout bytes.Buffer
+	if err := run([]string{"--eol=mac", "lorum.txt"}, strings.NewReader(""), &stdout, io.Discard); err == nil {
+		t.Error("Expected an error for an invalid --eol value")
+	}
+}
+
+func TestRunEncoding(t *testing.T) {
+	utf16le := "\xff\xfe" + "h\x00\xe9\x00l\x00l\x00o\x00 \x00w\x00\xf6\x00r\x00l\x00d\x00\n\x00"
+	paths := writeTestFiles(t, utf16le, "h\xe9llo w\xf6rld\n")
+
+	want := fmt.Sprintf(" 1  2 12 26 %s\n", paths[0])
+	if got := runOutput(t, "-l", "-w", "-m", "-c", "--encoding=auto", paths[0]); got != want {
+		t.Errorf("--encoding=auto output = %q, want %q", got, want)
+	}
+	want = fmt.Sprintf(" 1  2 12 12 %s\n", paths[1])
+	if got := runOutput(t, "-l", "-w", "-m", "-c", "--encoding=latin1", paths[1]); got != want {
+		t.Errorf("--encoding=latin1 output = %q, want %q", got, want)
+	}
+	// a byte order mark keeps UTF-16 files from being skipped as binary
+	want = fmt.Sprintf("1 %s\n", paths[0])
+	if got := runOutput(t, "-l", "-r", "--total=never", "--encoding=auto", paths[0]); got != want {
+		t.Errorf("-r output = %q, want %q", got, want)
+	}
+
+	var stdout bytes.Buffer
+	if err := run([]string{"--encoding=ebcdic", "lorum.txt"}, strings.NewReader(""), &stdout, io.Discard); err == nil {
+		t.Error("Expected an error for an invalid --encoding value")
+	}
+}
+
+func TestSkipCounts(t *testing.T) {
+	tests := []struct {
+		args      []string
+		linesOnly bool
+		noWidth   bool
+	}{
+		{[]string{"-l"}, true, true},
+		{[]string{"-l", "-c"}, true, true},
+		{[]string{"-c", "-z"}, true, true},
+		{nil, false, true},
+		{[]string{"-m"}, false, true},
+		{[]string{"-l", "-L"}, false, false},
+		{[]string{"-l", "--longest-line"}, false, false},
+		{[]string{"-l", "--eol-report"}, false, true},
+		{[]string{"-l", "--format=json"}, false, true},
+		{[]string{"-l", "--printf", "{lines}\n"}, false, false},
+		{[]string{"-l", "--save", "snapshot.json"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			opts, err := parseArgs(tt.args, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.count.LinesOnly != tt.linesOnly || opts.count.NoWidth != tt.noWidth {
+				t.Errorf("LinesOnly = %v and NoWidth = %v, want %v and %v",
+					opts.count.LinesOnly, opts.count.NoWidth, tt.linesOnly, tt.noWidth)
+			}
+		})
+	}
+}
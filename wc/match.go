@@ -0,0 +1,27 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// matchColumns are the matches of the -e patterns and the lines with at
+// least one
+var matchColumns = []column{
+	{"matches", func(c counts) int64 { return c.Matches }, nil, false},
+	{"matching_lines", func(c counts) int64 { return c.MatchingLines }, nil, false},
+}
+
+// compilePatterns returns a regular expression matching any of patterns,
+// like grep with several -e. Each pattern is compiled on its own first so an
+// error names it.
+func compilePatterns(patterns []string) (*regexp.Regexp, error) {
+	alternatives := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+		alternatives[i] = "(?:" + pattern + ")"
+	}
+	return regexp.Compile(strings.Join(alternatives, "|"))
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunPatterns(t *testing.T) {
+	paths := writeTestFiles(t, "error: disk full\nok\nerror, error\n", "warning\r\nok\r\n")
+
+	want := fmt.Sprintf(" 3  3  2 %s\n 2  1  1 %s\n 5  4  3 total\n", paths[0], paths[1])
+	if got := runOutput(t, "-l", "-e", "error", "-e", "^w.*g$", paths[0], paths[1]); got != want {
+		t.Errorf("-e output = %q, want %q", got, want)
+	}
+	want = "path,lines,words,chars,bytes,matches,matching_lines,error\n-,2,2,8,8,1,1,\n"
+	if got := runWithStdin(t, "one\ntwo\n", "-l", "-e", "(?i)ONE", "--format=csv"); got != want {
+		t.Errorf("--format=csv output = %q, want %q", got, want)
+	}
+
+	var stdout bytes.Buffer
+	if err := run([]string{"-e", "a(", "lorum.txt"}, strings.NewReader(""), &stdout, io.Discard); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// totalMode controls when the total row is printed, same as GNU wc --total
+type totalMode string
+
+const (
+	totalAuto   totalMode = "auto"   // print the total when counting more than one file
+	totalAlways totalMode = "always" // always print the total
+	totalOnly   totalMode = "only"   // print only the total, without file rows
+	totalNever  totalMode = "never"  // never print the total
+)
+
+func (m *totalMode) String() string {
+	return string(*m)
+}
+
+func (m *totalMode) Set(value string) error {
+	switch mode := totalMode(value); mode {
+	case totalAuto, totalAlways, totalOnly, totalNever:
+		*m = mode
+		return nil
+	default:
+		return fmt.Errorf("invalid total mode %q, expected auto, always, only or never", value)
+	}
+}
+
+// print reports whether the total row of the given number of files is printed
+func (m totalMode) print(files int) bool {
+	switch m {
+	case totalAlways, totalOnly:
+		return true
+	case totalAuto:
+		return files > 1
+	default:
+		return false
+	}
+}
+
+// numberWidth computes the column width the way GNU wc does: wide enough for
+// the sum of the sizes of all regular files, and at least 7 when an input is
+// not a regular file. The sizes of compressed files and archives say nothing
+// of the counts of their content, so they are padded to 7 too. A single
+// count of a single file is not padded.
+func numberWidth(opts *options, stdin io.Reader) int {
+	if len(opts.files) == 1 && len(opts.columns()) == 1 {
+		return 1
+	}
+
+	minWidth := 1
+	if opts.decompress || opts.archive {
+		minWidth = 7
+	}
+	var regularTotal int64
+	for _, filename := range opts.files {
+		info, err := statInput(filename, stdin)
+		switch {
+		case err != nil:
+			continue
+		case info != nil && info.Mode().IsRegular():
+			regularTotal += info.Size()
+		default:
+			minWidth = 7
+		}
+	}
+
+	return max(len(strconv.FormatInt(regularTotal, 10)), minWidth)
+}
+
+// statInput returns the file info of filename. Standard input is only
+// stat'ed when it is an open file such as a redirection, for any other
+// reader the info is nil.
+func statInput(filename string, stdin io.Reader) (os.FileInfo, error) {
+	if !isStdin(filename) {
+		return os.Stat(filename)
+	}
+	if file, ok := stdin.(*os.File); ok {
+		return file.Stat()
+	}
+	return nil, nil
+}
+
+// writeCounts prints one row of right aligned counts followed by the name,
+// the name is omitted when empty
+func writeCounts(w io.Writer, width int, values []int64, name string) {
+	var row strings.Builder
+	for i, value := range values {
+		if i > 0 {
+			row.WriteByte(' ')
+		}
+		fmt.Fprintf(&row, "%*d", width, value)
+	}
+	if name != "" {
+		row.WriteByte(' ')
+		row.WriteString(name)
+	}
+	row.WriteByte('\n')
+
+	_, _ = io.WriteString(w, row.String())
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"wc/count"
+)
+
+// outputFormat is the format of the rows printed by wc
+type outputFormat string
+
+const (
+	formatText outputFormat = "text" // space separated columns like GNU wc
+	formatJSON outputFormat = "json" // an object per file and a total object
+	formatCSV  outputFormat = "csv"  // comma separated values with a header
+	formatTSV  outputFormat = "tsv"  // tab separated values with a header
+)
+
+func (f *outputFormat) String() string {
+	return string(*f)
+}
+
+func (f *outputFormat) Set(value string) error {
+	switch format := outputFormat(value); format {
+	case formatText, formatJSON, formatCSV, formatTSV:
+		*f = format
+		return nil
+	default:
+		return fmt.Errorf("invalid format %q, expected text, json, csv or tsv", value)
+	}
+}
+
+// reporter prints the counts of every file and the total in an output format
+type reporter interface {
+	// file prints the counts of one file, or the error counting it, unless
+	// only the total is printed
+	file(r result)
+	// total prints the total of all files
+	total(c counts)
+	// languageTotal prints the total of the files of a language, with --code
+	languageTotal(c counts)
+	// close flushes the output
+	close() error
+}
+
+func newReporter(opts *options, w io.Writer, width int) reporter {
+	if opts.top > 0 {
+		return &topReporter{format: opts.format, n: opts.top, w: w, words: count.NewFrequencies()}
+	}
+	if opts.template != nil {
+		return &templateReporter{template: opts.template, only: opts.total == totalOnly, w: bufio.NewWriter(w)}
+	}
+	switch opts.format {
+	case formatJSON:
+		return &jsonReporter{columns: opts.fieldColumns(), only: opts.total == totalOnly, stats: opts.histograms(), w: bufio.NewWriter(w)}
+	case formatCSV, formatTSV:
+		cw := csv.NewWriter(w)
+		if opts.format == formatTSV {
+			cw.Comma = '\t'
+		}
+		return &csvReporter{columns: opts.fieldColumns(), only: opts.total == totalOnly, w: cw}
+	default:
+		return &textReporter{opts: opts, w: w, width: width}
+	}
+}
+
+// displayName is the name of an input in machine readable formats, where
+// standard input is always named "-"
+func displayName(name string) string {
+	if name == "" {
+		return "-"
+	}
+	return name
+}
+
+// textReporter prints aligned columns followed by the file name
+type textReporter struct {
+	opts  *options
+	w     io.Writer
+	width int
+}
+
+func (r *textReporter) file(res result) {
+	if !res.printed() || r.opts.total == totalOnly {
+		return
+	}
+	r.row(r.width, res.counts, res.name)
+}
+
+func (r *textReporter) total(c counts) {
+	if r.opts.total == totalOnly {
+		// the grand total alone is not padded nor named
+		r.row(1, c, "")
+		return
+	}
+	r.row(r.width, c, "total")
+}
+
+func (r *textReporter) languageTotal(c counts) {
+	r.row(r.width, c, "total")
+}
+
+// row prints the counts of c, then their texts and the name, followed by
+// the histogram of the line lengths with --stats
+func (r *textReporter) row(width int, c counts, name string) {
+	values, labels := r.opts.values(c)
+	if name != "" {
+		labels = append(labels, name)
+	}
+	writeCounts(r.w, width, values, strings.Join(labels, " "))
+	if r.opts.histograms() {
+		writeHistogram(r.w, c.lineStats())
+	}
+}
+
+func (r *textReporter) close() error {
+	return nil
+}
+
+// jsonReporter prints a single document with the list of files and the
+// total, one file per line so the output can be streamed
+type jsonReporter struct {
+	columns []column
+	only    bool
+	stats   bool // add the histogram of the line lengths
+	w       *bufio.Writer
+	rows    int // number of objects in the list of files
+	files   int
+	errors  int
+	totals  *counts
+	// totals of each language, printed before the total
+	languages []counts
+}
+
+// fields appends the path, when not empty, the selected counts and the
+// histogram of an object to buf, without the braces
+func (r *jsonReporter) fields(buf []byte, name string, c counts) []byte {
+	if name != "" {
+		buf = append(buf, `"path":`...)
+		buf = appendJSONString(buf, name)
+		buf = append(buf, ',')
+	}
+	for i, col := range r.columns {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendJSONString(buf, col.name)
+		buf = append(buf, ':')
+		switch {
+		case col.label != nil && col.number:
+			buf = append(buf, col.label(c)...)
+		case col.label != nil:
+			buf = appendJSONString(buf, col.label(c))
+		default:
+			buf = strconv.AppendInt(buf, col.value(c), 10)
+		}
+	}
+	if r.stats {
+		buf = append(buf, `,"histogram":`...)
+		buf = appendHistogram(buf, c.lineStats())
+	}
+	return buf
+}
+
+func (r *jsonReporter) file(res result) {
+	// the files of an archive are counted by the archive itself
+	if !res.member {
+		r.files++
+		if res.err != nil {
+			r.errors++
+		}
+	}
+	if r.only {
+		return
+	}
+
+	if r.rows == 0 {
+		_, _ = r.w.WriteString("{\"files\":[\n")
+	} else {
+		_, _ = r.w.WriteString(",\n")
+	}
+	r.rows++
+
+	buf := r.fields([]byte{'{'}, displayName(res.name), res.counts)
+	if res.err != nil {
+		buf = append(buf, `,"error":`...)
+		buf = appendJSONString(buf, errorMessage(res.err))
+	}
+	_, _ = r.w.Write(append(buf, '}'))
+}
+
+func (r *jsonReporter) total(c counts) {
+	r.totals = &c
+}
+
+func (r *jsonReporter) languageTotal(c counts) {
+	r.languages = append(r.languages, c)
+}
+
+func (r *jsonReporter) close() error {
+	if r.rows == 0 {
+		_, _ = r.w.WriteString("{\"files\":[")
+	} else {
+		_, _ = r.w.WriteString("\n")
+	}
+	_, _ = r.w.WriteString("]")
+	if len(r.languages) > 0 {
+		_, _ = r.w.WriteString(",\"languages\":[")
+		for i, c := range r.languages {
+			buf := []byte("\n{")
+			if i > 0 {
+				buf = []byte(",\n{")
+			}
+			_, _ = r.w.Write(append(r.fields(buf, "", c), '}'))
+		}
+		_, _ = r.w.WriteString("\n]")
+	}
+	if r.totals != nil {
+		buf := r.fields([]byte(`,"total":{`), "", *r.totals)
+		buf = append(buf, `,"files":`...)
+		buf = strconv.AppendInt(buf, int64(r.files-r.errors), 10)
+		buf = append(buf, `,"errors":`...)
+		buf = strconv.AppendInt(buf, int64(r.errors), 10)
+		_, _ = r.w.Write(append(buf, '}'))
+	}
+	_, _ = r.w.WriteString("}\n")
+
+	return r.w.Flush()
+}
+
+// appendJSONString appends s to buf as a JSON string
+func appendJSONString(buf []byte, s string) []byte {
+	quoted, _ := json.Marshal(s)
+	return append(buf, quoted...)
+}
+
+// csvReporter prints a header with the column names, then a record per file
+// and the total, with the path in the first field and the error of a file
+// that failed in the last one
+type csvReporter struct {
+	columns []column
+	only    bool
+	w       *csv.Writer
+	header  bool
+}
+
+func (r *csvReporter) writeHeader() {
+	if r.header {
+		return
+	}
+	r.header = true
+	header := []string{"path"}
+	for _, col := range r.columns {
+		header = append(header, col.name)
+	}
+	_ = r.w.Write(append(header, "error"))
+}
+
+func (r *csvReporter) record(name string, c counts, err error) {
+	r.writeHeader()
+	record := []string{name}
+	for _, col := range r.columns {
+		if col.label != nil {
+			record = append(record, col.label(c))
+		} else {
+			record = append(record, strconv.FormatInt(col.value(c), 10))
+		}
+	}
+	var message string
+	if err != nil {
+		message = errorMessage(err)
+	}
+	_ = r.w.Write(append(record, message))
+}
+
+func (r *csvReporter) file(res result) {
+	if r.only {
+		return
+	}
+	r.record(displayName(res.name), res.counts, res.err)
+}
+
+func (r *csvReporter) total(c counts) {
+	r.record("total", c, nil)
+}
+
+func (r *csvReporter) languageTotal(c counts) {
+	r.record("total", c, nil)
+}
+
+func (r *csvReporter) close() error {
+	r.writeHeader()
+	r.w.Flush()
+	return r.w.Error()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// jsonOutput is the document printed by --format=json
+type jsonOutput struct {
+	Files []map[string]any `json:"files"`
+	Total map[string]any   `json:"total"`
+}
+
+func TestRunJSONFormat(t *testing.T) {
+	paths := writeTestFiles(t, "hello world\n", "one two three\n")
+
+	var got jsonOutput
+	out := runWithStdin(t, "a b\n", "--format=json", "-m", paths[0], paths[1], "-")
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+
+	wantFiles := []map[string]any{
+		{"path": paths[0], "lines": 1.0, "words": 2.0, "chars": 12.0, "bytes": 12.0},
+		{"path": paths[1], "lines": 1.0, "words": 3.0, "chars": 14.0, "bytes": 14.0},
+		{"path": "-", "lines": 1.0, "words": 2.0, "chars": 4.0, "bytes": 4.0},
+	}
+	if fmt.Sprint(got.Files) != fmt.Sprint(wantFiles) {
+		t.Errorf("files = %v, want %v", got.Files, wantFiles)
+	}
+	wantTotal := map[string]any{"lines": 3.0, "words": 7.0, "chars": 30.0, "bytes": 30.0, "files": 3.0, "errors": 0.0}
+	if fmt.Sprint(got.Total) != fmt.Sprint(wantTotal) {
+		t.Errorf("total = %v, want %v", got.Total, wantTotal)
+	}
+}
+
+func TestRunJSONFormatError(t *testing.T) {
+	paths := writeTestFiles(t, "hello\n")
+	missing := filepath.Join(filepath.Dir(paths[0]), "missing \"quoted\".txt")
+
+	var stdout bytes.Buffer
+	if err := run([]string{"--format=json", paths[0], missing}, strings.NewReader(""), &stdout, io.Discard); err == nil {
+		t.Fatal("Expected an error for a missing file")
+	}
+
+	var got jsonOutput
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+	}
+	if len(got.Files) != 2 || got.Files[1]["path"] != missing || got.Files[1]["error"] == nil {
+		t.Errorf("files = %v, want the error of %s", got.Files, missing)
+	}
+}
+
+func TestRunSeparatedValuesError(t *testing.T) {
+	paths := writeTestFiles(t, "hello\n")
+	missing := filepath.Join(filepath.Dir(paths[0]), "missing.txt")
+
+	var stdout bytes.Buffer
+	if err := run([]string{"--format=csv", paths[0], missing}, strings.NewReader(""), &stdout, io.Discard); err == nil {
+		t.Fatal("Expected an error for a missing file")
+	}
+	// the failed input has a record like in JSON, and is not in the total
+	want := fmt.Sprintf("path,lines,words,chars,bytes,error\n%s,1,1,6,6,\n%s,0,0,0,0,No such file or directory\ntotal,1,1,6,6,\n", paths[0], missing)
+	if stdout.String() != want {
+		t.Errorf("run() output =\n%s\nwant\n%s", stdout.String(), want)
+	}
+}
+
+func TestRunSeparatedValuesFormats(t *testing.T) {
+	paths := writeTestFiles(t, "hello world\n", "one, two\n")
+
+	tests := []struct {
+		format string
+		args   []string
+		want   string
+	}{
+		{"csv", paths, fmt.Sprintf("path,lines,words,chars,bytes,error\n%s,1,2,12,12,\n%s,1,2,9,9,\ntotal,2,4,21,21,\n", paths[0], paths[1])},
+		{"tsv", paths[:1], fmt.Sprintf("path\tlines\twords\tchars\tbytes\terror\n%s\t1\t2\t12\t12\t\n", paths[0])},
+		{"csv", append([]string{"--total=only", "-w"}, paths...), "path,lines,words,chars,bytes,error\ntotal,2,4,21,21,\n"},
+		{"csv", append([]string{"-L"}, paths...), fmt.Sprintf("path,lines,words,chars,bytes,max_line_length,error\n%s,1,2,12,12,11,\n%s,1,2,9,9,8,\ntotal,2,4,21,21,11,\n", paths[0], paths[1])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got := runOutput(t, append([]string{"--format=" + tt.format}, tt.args...)...)
+			if got != tt.want {
+				t.Errorf("run() output =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunInvalidFormat(t *testing.T) {
+	var stdout bytes.Buffer
+	if err := run([]string{"--format=xml", "lorum.txt"}, strings.NewReader(""), &stdout, io.Discard); err == nil {
+		t.Error("Expected an error for an invalid --format value")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"wc/count"
+)
+
+// histogramWidth is the length of the bar of the most common line lengths
+const histogramWidth = 40
+
+// lineStats returns the line length distribution of c, empty when the input
+// could not be counted
+func (c counts) lineStats() *count.LineStats {
+	if c.stats == nil {
+		return count.NewLineStats()
+	}
+	return c.stats
+}
+
+// statsColumns are the line length statistics printed with --stats
+var statsColumns = []column{
+	{"line_min", func(c counts) int64 { return c.lineStats().Min }, nil, false},
+	{"line_median", func(c counts) int64 { return c.lineStats().Quantile(0.5) }, nil, false},
+	{"line_p90", func(c counts) int64 { return c.lineStats().Quantile(0.9) }, nil, false},
+	{"line_p99", func(c counts) int64 { return c.lineStats().Quantile(0.99) }, nil, false},
+	{"line_max", func(c counts) int64 { return c.lineStats().Max }, nil, false},
+	{"line_mean", nil, func(c counts) string { return strconv.FormatFloat(c.lineStats().Mean(), 'f', 1, 64) }, true},
+}
+
+// binLabel returns the range of lengths of a bin, such as "4-7"
+func binLabel(bin count.Bin) string {
+	if bin.Min == bin.Max {
+		return strconv.FormatInt(bin.Min, 10)
+	}
+	return fmt.Sprintf("%d-%d", bin.Min, bin.Max)
+}
+
+// writeHistogram prints a bar per range of line lengths, the longest bar
+// being the most common range
+func writeHistogram(w io.Writer, s *count.LineStats) {
+	bins := s.Histogram()
+	labelWidth, most := 0, int64(0)
+	for _, bin := range bins {
+		labelWidth = max(labelWidth, len(binLabel(bin)))
+		most = max(most, bin.Lines)
+	}
+	countWidth := len(strconv.FormatInt(most, 10))
+
+	var out strings.Builder
+	for _, bin := range bins {
+		// a range with lines always has a bar
+		bar := int((bin.Lines*histogramWidth + most - 1) / most)
+		line := fmt.Sprintf("  %-*s %*d %s", labelWidth, binLabel(bin), countWidth, bin.Lines, strings.Repeat("#", bar))
+		out.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	_, _ = io.WriteString(w, out.String())
+}
+
+// appendHistogram appends the histogram of s to buf as a JSON array
+func appendHistogram(buf []byte, s *count.LineStats) []byte {
+	buf = append(buf, '[')
+	for i, bin := range s.Histogram() {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"min":`...)
+		buf = strconv.AppendInt(buf, bin.Min, 10)
+		buf = append(buf, `,"max":`...)
+		buf = strconv.AppendInt(buf, bin.Max, 10)
+		buf = append(buf, `,"lines":`...)
+		buf = strconv.AppendInt(buf, bin.Lines, 10)
+		buf = append(buf, '}')
+	}
+	return append(buf, ']')
+}
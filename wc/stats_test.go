@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestRunStats(t *testing.T) {
+	paths := writeTestFiles(t, "a\nabc\r\nabcdefgh\n\n")
+
+	want := " 4  0  1  8  8  8 3.0 " + paths[0] + "\n" +
+		"  0    1 ########################################\n" +
+		"  1    1 ########################################\n" +
+		"  2-3  1 ########################################\n" +
+		"  4-7  0\n" +
+		"  8-15 1 ########################################\n"
+	if got := runOutput(t, "-l", "--stats", paths[0]); got != want {
+		t.Errorf("--stats output = %q, want %q", got, want)
+	}
+
+	want = `{"files":[` + "\n" + `{"path":"-","lines":2,"words":2,"chars":9,"bytes":9,"line_min":2,"line_median":2,"line_p90":5,"line_p99":5,"line_max":5,"line_mean":3.5,` +
+		`"histogram":[{"min":0,"max":0,"lines":0},{"min":1,"max":1,"lines":0},{"min":2,"max":3,"lines":1},{"min":4,"max":7,"lines":1}]}` + "\n]}\n"
+	if got := runWithStdin(t, "ab\nabcde\n", "-l", "--stats", "--format=json"); got != want {
+		t.Errorf("--format=json output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// template is a --printf template: literal text and fields replaced by the
+// counts and the path of each row
+type template []templatePart
+
+// templatePart is a literal text, or a field when name is set
+type templatePart struct {
+	text  string
+	name  string
+	col   column // the column of the field, unless it is the path
+	width int
+	// '<' pads on the right, '>' on the left and '^' on both sides, 0
+	// aligns numbers right and texts left
+	align byte
+}
+
+// templateEscapes are the characters written with a backslash in templates
+var templateEscapes = map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '{': '{', '}': '}'}
+
+// parseTemplate parses a template of literal text, backslash escapes and
+// fields such as {lines}, {path:<20} or {words:8}. A field is the path or
+// one of columns, or of known when it is not in columns.
+func parseTemplate(s string, columns, known []column) (template, error) {
+	var t template
+	var text strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return nil, errors.New("template ends with a backslash")
+			}
+			escaped, ok := templateEscapes[s[i+1]]
+			if !ok {
+				return nil, fmt.Errorf("unknown escape \\%c in template", s[i+1])
+			}
+			text.WriteByte(escaped)
+			i++
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed field %q in template", s[i:])
+			}
+			part, err := parseField(s[i+1:i+end], columns, known)
+			if err != nil {
+				return nil, err
+			}
+			if text.Len() > 0 {
+				t = append(t, templatePart{text: text.String()})
+				text.Reset()
+			}
+			t = append(t, part)
+			i += end
+		case '}':
+			return nil, fmt.Errorf("unopened field at %q in template, write \\} for a brace", s[i:])
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+	if text.Len() > 0 {
+		t = append(t, templatePart{text: text.String()})
+	}
+	return t, nil
+}
+
+// parseField parses the inside of the braces of a field: a name, then
+// optionally a colon, an alignment and a width
+func parseField(field string, columns, known []column) (templatePart, error) {
+	name, spec, _ := strings.Cut(field, ":")
+	part := templatePart{name: name}
+	if spec != "" && strings.ContainsRune("<>^", rune(spec[0])) {
+		part.align = spec[0]
+		spec = spec[1:]
+	}
+	if spec != "" {
+		width, err := strconv.Atoi(spec)
+		if err != nil || width < 0 {
+			return part, fmt.Errorf("invalid width %q of field {%s} in template", spec, field)
+		}
+		part.width = width
+	}
+
+	if name == "path" {
+		return part, nil
+	}
+	for _, list := range [][]column{columns, known} {
+		for _, col := range list {
+			if col.name == name {
+				part.col = col
+				return part, nil
+			}
+		}
+	}
+	return part, fmt.Errorf("unknown field {%s} in template", name)
+}
+
+// appendRow appends the row of the counts c of the input name to buf
+func (t template) appendRow(buf []byte, c counts, name string) []byte {
+	for _, part := range t {
+		if part.name == "" {
+			buf = append(buf, part.text...)
+			continue
+		}
+
+		var value string
+		align := byte('>')
+		switch {
+		case part.name == "path":
+			value, align = name, '<'
+		case part.col.label != nil:
+			value = part.col.label(c)
+			if !part.col.number {
+				align = '<'
+			}
+		default:
+			value = strconv.FormatInt(part.col.value(c), 10)
+		}
+		if part.align != 0 {
+			align = part.align
+		}
+		buf = appendPadded(buf, value, part.width, align)
+	}
+	return buf
+}
+
+// appendPadded appends value to buf padded with spaces to width characters
+func appendPadded(buf []byte, value string, width int, align byte) []byte {
+	padding := max(width-utf8.RuneCountInString(value), 0)
+	var left int
+	switch align {
+	case '>':
+		left = padding
+	case '^':
+		left = padding / 2
+	}
+	buf = append(buf, strings.Repeat(" ", left)...)
+	buf = append(buf, value...)
+	return append(buf, strings.Repeat(" ", padding-left)...)
+}
+
+// templateReporter prints every row with a --printf template, the template
+// ends the rows itself
+type templateReporter struct {
+	template template
+	only     bool
+	w        *bufio.Writer
+}
+
+func (r *templateReporter) row(c counts, name string) {
+	_, _ = r.w.Write(r.template.appendRow(nil, c, name))
+}
+
+func (r *templateReporter) file(res result) {
+	if !res.printed() || r.only {
+		return
+	}
+	r.row(res.counts, displayName(res.name))
+}
+
+func (r *templateReporter) total(c counts) {
+	r.row(c, "total")
+}
+
+func (r *templateReporter) languageTotal(c counts) {
+	r.row(c, "total")
+}
+
+func (r *templateReporter) close() error {
+	return r.w.Flush()
+}
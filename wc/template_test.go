@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	c := counts{}
+	c.Lines, c.Words = 3, 12
+	columns := []column{linesColumn, wordsColumn}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{lines} {path}\n", "3 a.txt\n"},
+		{`{lines}\t{words}\t{path}\n`, "3\t12\ta.txt\n"},
+		{"[{lines:4}][{path:8}]", "[   3][a.txt   ]"},
+		{"[{lines:<4}][{path:>8}][{words:^6}]", "[3   ][   a.txt][  12  ]"},
+		{`\{{lines}\} \\`, `{3} \`},
+		{"{lines:1}", "3"},
+		{"{eol}", "none"},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := parseTemplate(tt.template, columns, countedColumns)
+		if err != nil {
+			t.Errorf("parseTemplate(%q) returned error: %v", tt.template, err)
+			continue
+		}
+		if got := string(tmpl.appendRow(nil, c, "a.txt")); got != tt.want {
+			t.Errorf("template %q printed %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"{lines", "lines}", "{unknown}", "{lines:x}", "{lines:-3}", `\q`, `\`} {
+		if _, err := parseTemplate(invalid, columns, countedColumns); err == nil {
+			t.Errorf("parseTemplate(%q) returned no error", invalid)
+		}
+	}
+}
+
+func TestRunPrintf(t *testing.T) {
+	paths := writeTestFiles(t, "one two\nthree\n", "four\n")
+
+	want := fmt.Sprintf("2\t3\t%s\n1\t1\t%s\n3\t4\ttotal\n", paths[0], paths[1])
+	if got := runOutput(t, "--printf", `{lines}\t{words}\t{path}\n`, paths[0], paths[1]); got != want {
+		t.Errorf("--printf output = %q, want %q", got, want)
+	}
+	// fields of other options need them
+	if got, want := runWithStdin(t, "a\n\nb\n", "--code", "--printf", "{path} {code} {blank} {language}\n"), "- 2 1 Text\n"; got != want {
+		t.Errorf("--printf --code output = %q, want %q", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"--printf", "{code}\n", paths[0]},
+		{"--printf", "{lines}\n", "--format=json", paths[0]},
+		{"--printf", "{lines}\n", "--top", "3", paths[0]},
+	} {
+		var stdout bytes.Buffer
+		if err := run(args, strings.NewReader(""), &stdout, io.Discard); err == nil {
+			t.Errorf("run(%v) returned no error", args)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"wc/count"
+)
+
+// readStopWords reads a file of words that are not counted by --top, one
+// per line, blank lines and lines starting with # are ignored
+func readStopWords(filename string, fold bool) (_ map[string]bool, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile(file, &err)
+
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		if fold {
+			word = strings.ToLower(word)
+		}
+		words[word] = true
+	}
+
+	return words, scanner.Err()
+}
+
+// topReporter prints the most frequent words of all inputs instead of their
+// counts
+type topReporter struct {
+	format outputFormat
+	n      int
+	w      io.Writer
+	words  *count.Frequencies
+}
+
+func (r *topReporter) file(res result) {
+	if res.err != nil || res.member || res.counts.words == nil {
+		return
+	}
+	r.words.Add(res.counts.words)
+}
+
+func (r *topReporter) total(counts) {}
+
+func (r *topReporter) languageTotal(counts) {}
+
+func (r *topReporter) close() error {
+	top := r.words.Top(r.n)
+
+	switch r.format {
+	case formatJSON:
+		w := bufio.NewWriter(r.w)
+		_, _ = w.WriteString("{\"words\":[")
+		for i, word := range top {
+			if i > 0 {
+				_, _ = w.WriteString(",")
+			}
+			buf := append([]byte("\n{\"word\":"), appendJSONString(nil, word.Word)...)
+			buf = append(buf, `,"count":`...)
+			buf = strconv.AppendInt(buf, word.Count, 10)
+			_, _ = w.Write(append(buf, '}'))
+		}
+		if len(top) > 0 {
+			_, _ = w.WriteString("\n")
+		}
+		_, _ = w.WriteString("]}\n")
+		return w.Flush()
+
+	case formatCSV, formatTSV:
+		w := csv.NewWriter(r.w)
+		if r.format == formatTSV {
+			w.Comma = '\t'
+		}
+		_ = w.Write([]string{"word", "count"})
+		for _, word := range top {
+			_ = w.Write([]string{word.Word, strconv.FormatInt(word.Count, 10)})
+		}
+		w.Flush()
+		return w.Error()
+
+	default:
+		// counts are aligned on the most frequent word, like uniq -c
+		width := 1
+		if len(top) > 0 {
+			width = len(strconv.FormatInt(top[0].Count, 10))
+		}
+		for _, word := range top {
+			writeCounts(r.w, width, []int64{word.Count}, word.Word)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTop(t *testing.T) {
+	paths := writeTestFiles(t, "The cat and the dog.\n", "the end, The END\n")
+	stopWords := filepath.Join(t.TempDir(), "stop")
+	if err := os.WriteFile(stopWords, []byte("# articles\nTHE\n\nand\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"case sensitive", []string{"--top", "2"}, "2 The\n2 the\n"},
+		{"fold", []string{"--top", "2", "--fold"}, "4 the\n2 end\n"},
+		{"min length", []string{"--top", "3", "--fold", "--min-length", "3"}, "4 the\n2 end\n1 and\n"},
+		{"stop words", []string{"--top", "3", "--fold", "--stop-words", stopWords}, "2 end\n1 cat\n1 dog\n"},
+		{"json", []string{"--top", "1", "--fold", "--format", "json"}, "{\"words\":[\n{\"word\":\"the\",\"count\":4}\n]}\n"},
+		{"csv", []string{"--top", "1", "--fold", "--format", "csv"}, "word,count\nthe,4\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runOutput(t, append(tt.args, paths...)...); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTopChunksMatchesSerial(t *testing.T) {
+	previous := minChunkSize
+	minChunkSize = 64
+	t.Cleanup(func() { minChunkSize = previous })
+
+	paths := writeTestFiles(t, strings.Repeat("héllo wörld, Hello\tworld again\n", 200))
+
+	serial := runOutput(t, "--top", "5", "--fold", paths[0])
+	chunked := runOutput(t, "--chunks", "8", "--top", "5", "--fold", paths[0])
+	if serial != chunked {
+		t.Errorf("chunked output = %q, want %q", chunked, serial)
+	}
+}
+
+func TestRunTopInvalid(t *testing.T) {
+	var out strings.Builder
+	for _, args := range [][]string{{"--top", "-1"}, {"--top", "1", "--min-length", "-2"}, {"--top", "1", "--stop-words", "missing"}} {
+		if err := run(args, strings.NewReader(""), &out, io.Discard); err == nil {
+			t.Errorf("run(%v) succeeded, want an error", args)
+		}
+	}
+}
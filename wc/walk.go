@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"wc/count"
+)
+
+// binarySniffSize is how much of a file is checked for NUL bytes, like git
+const binarySniffSize = 8000
+
+// errBinary is returned when a file is skipped because it looks binary
+var errBinary = errors.New("binary file")
+
+// stringList is a flag that can be repeated, every value is kept
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// globPattern is a glob where * and ? do not match a slash, ** matches any
+// number of directories and a pattern without a slash matches the base name
+type globPattern struct {
+	re       *regexp.Regexp
+	anchored bool // the pattern contains a slash, it matches the whole path
+}
+
+func compileGlob(pattern string) (globPattern, error) {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			switch {
+			case strings.HasPrefix(pattern[i:], "**/"):
+				expr.WriteString("(?:.*/)?")
+				i += 2
+			case strings.HasPrefix(pattern[i:], "**"):
+				expr.WriteString(".*")
+				i++
+			default:
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return globPattern{}, err
+	}
+	return globPattern{re: re, anchored: anchored}, nil
+}
+
+// match reports whether the slash separated path matches the pattern
+func (g globPattern) match(rel string) bool {
+	if g.anchored {
+		return g.re.MatchString(rel)
+	}
+	return g.re.MatchString(path.Base(rel))
+}
+
+// ignoreRule is a line of a .gitignore file
+type ignoreRule struct {
+	glob    globPattern
+	negate  bool // the pattern starts with ! and includes the path again
+	dirOnly bool // the pattern ends with / and only matches directories
+}
+
+// parseGitignore reads the rules of a .gitignore file
+func parseGitignore(r io.Reader) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		glob, err := compileGlob(line)
+		if err != nil {
+			// git ignores invalid patterns too
+			continue
+		}
+		rule.glob = glob
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+// walkOptions selects the files found in directories by -r
+type walkOptions struct {
+	recursive bool
+	include   stringList
+	exclude   stringList
+	gitignore bool // skip the files ignored by .gitignore files
+}
+
+// walker lists the files of a directory tree
+type walker struct {
+	opts    *walkOptions
+	include []globPattern
+	exclude []globPattern
+	// rules of the .gitignore file of each directory, by slash separated path
+	// relative to the root of the walk
+	ignores map[string][]ignoreRule
+	// directories that cannot be read are reported to stderr and skipped
+	stderr io.Writer
+	failed bool
+}
+
+func newWalker(opts *walkOptions) (*walker, error) {
+	w := &walker{opts: opts}
+	for _, pattern := range opts.include {
+		glob, err := compileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		w.include = append(w.include, glob)
+	}
+	for _, pattern := range opts.exclude {
+		glob, err := compileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		w.exclude = append(w.exclude, glob)
+	}
+	return w, nil
+}
+
+// expandFiles replaces the directories of files by the files they contain
+// when counting recursively, in lexical order. A directory that cannot be
+// read is reported to stderr like a file that cannot be counted, the walk
+// goes on and failed is true.
+func expandFiles(files []string, opts *walkOptions, stderr io.Writer) (expanded []string, failed bool, err error) {
+	if !opts.recursive {
+		return files, false, nil
+	}
+	w, err := newWalker(opts)
+	if err != nil {
+		return nil, false, err
+	}
+	w.stderr = stderr
+
+	for _, filename := range files {
+		info, err := os.Stat(filename)
+		if isStdin(filename) || err != nil || !info.IsDir() {
+			// errors are reported when counting the file
+			expanded = append(expanded, filename)
+			continue
+		}
+
+		found, err := w.walk(filename)
+		if err != nil {
+			return nil, false, err
+		}
+		expanded = append(expanded, found...)
+	}
+	return expanded, w.failed, nil
+}
+
+// walk returns the files under root that are selected by the options
+func (w *walker) walk(root string) ([]string, error) {
+	w.ignores = make(map[string][]ignoreRule)
+
+	var files []string
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			w.failed = true
+			fmt.Fprintf(w.stderr, "wc: %v\n", &inputError{name, err})
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel == "." {
+				return w.loadGitignore(name, rel)
+			}
+			if w.excluded(rel) || w.opts.gitignore && (d.Name() == ".git" || w.ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			return w.loadGitignore(name, rel)
+		}
+
+		if !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			// links to directories are not followed, like find without -L,
+			// a broken link is reported when counted
+			if info, err := os.Stat(name); err == nil && info.IsDir() {
+				return nil
+			}
+		}
+		if w.excluded(rel) || !w.included(rel) || w.opts.gitignore && w.ignored(rel, false) {
+			return nil
+		}
+		files = append(files, name)
+		return nil
+	})
+
+	return files, err
+}
+
+func (w *walker) included(rel string) bool {
+	if len(w.include) == 0 {
+		return true
+	}
+	for _, glob := range w.include {
+		if glob.match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *walker) excluded(rel string) bool {
+	for _, glob := range w.exclude {
+		if glob.match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadGitignore reads the .gitignore file of the directory, if any
+func (w *walker) loadGitignore(dir, rel string) (err error) {
+	if !w.opts.gitignore {
+		return nil
+	}
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer closeFile(file, &err)
+
+	rules, err := parseGitignore(file)
+	if err != nil {
+		return err
+	}
+	w.ignores[rel] = rules
+	return nil
+}
+
+// ignored applies the .gitignore files from the root of the walk down to the
+// parent of rel, the last matching rule decides
+func (w *walker) ignored(rel string, isDir bool) bool {
+	ignored := false
+	parts := strings.Split(rel, "/")
+	for depth := range parts {
+		dir := "."
+		if depth > 0 {
+			dir = strings.Join(parts[:depth], "/")
+		}
+		relToDir := strings.Join(parts[depth:], "/")
+		for _, rule := range w.ignores[dir] {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.glob.match(relToDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// isBinary reports whether the start of r contains a NUL byte. UTF-16 text
+// is full of them, so it is never binary when it starts with a byte order
+// mark or when the encoding is UTF-16.
+func isBinary(r io.Reader, encoding count.Encoding) (bool, error) {
+	if encoding == count.EncodingUTF16LE || encoding == count.EncodingUTF16BE {
+		return false, nil
+	}
+	buf := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	if bytes.HasPrefix(buf[:n], []byte{0xff, 0xfe}) || bytes.HasPrefix(buf[:n], []byte{0xfe, 0xff}) {
+		return false, nil
+	}
+	return bytes.IndexByte(buf[:n], 0) >= 0, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates the files, given by slash separated path, under a
+// temporary directory and returns it
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestExpandFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".gitignore":         "*.log\nbuild/\n!keep.log\n",
+		".git/config":        "[core]\n",
+		"a.txt":              "a\n",
+		"b.go":               "package b\n",
+		"x.log":              "log\n",
+		"keep.log":           "keep\n",
+		"build/out.txt":      "out\n",
+		"sub/.gitignore":     "/local.txt\n",
+		"sub/c.go":           "package c\n",
+		"sub/local.txt":      "local\n",
+		"sub/deep/local.txt": "deep\n",
+		"vendor/v.go":        "package v\n",
+	})
+
+	tests := []struct {
+		name string
+		opts walkOptions
+		want []string
+	}{
+		{"recursive", walkOptions{}, []string{
+			".git/config", ".gitignore", "a.txt", "b.go", "build/out.txt", "keep.log", "sub/.gitignore",
+			"sub/c.go", "sub/deep/local.txt", "sub/local.txt", "vendor/v.go", "x.log",
+		}},
+		{"include", walkOptions{include: stringList{"*.go"}}, []string{"b.go", "sub/c.go", "vendor/v.go"}},
+		{"include path", walkOptions{include: stringList{"sub/**/*.txt"}}, []string{"sub/deep/local.txt", "sub/local.txt"}},
+		{"exclude", walkOptions{include: stringList{"*.go"}, exclude: stringList{"vendor"}}, []string{"b.go", "sub/c.go"}},
+		{"gitignore", walkOptions{gitignore: true}, []string{
+			".gitignore", "a.txt", "b.go", "keep.log", "sub/.gitignore", "sub/c.go", "sub/deep/local.txt", "vendor/v.go",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.recursive = true
+			files, _, err := expandFiles([]string{root}, &tt.opts, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(files))
+			for i, file := range files {
+				rel, err := filepath.Rel(root, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[i] = filepath.ToSlash(rel)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("expandFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandFilesNotRecursive(t *testing.T) {
+	files := []string{"a", "-", "b"}
+	got, _, err := expandFiles(files, &walkOptions{}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "a - b" {
+		t.Errorf("expandFiles() = %v, want the files unchanged", got)
+	}
+}
+
+func TestGlobPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "sub/main.go", true},
+		{"*.go", "main.go.txt", false},
+		{"sub/*.go", "sub/main.go", true},
+		{"sub/*.go", "sub/deep/main.go", false},
+		{"sub/**/*.go", "sub/deep/main.go", true},
+		{"sub/**/*.go", "sub/main.go", true},
+		{"/root.txt", "root.txt", true},
+		{"**/test", "a/b/test", true},
+		{"file?.txt", "file1.txt", true},
+		{"file[0-9].txt", "filex.txt", false},
+		{"file[!0-9].txt", "filex.txt", true},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			glob, err := compileGlob(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := glob.match(tt.path); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunRecursiveSkipsBinaryFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt":   "hello world\n",
+		"b.txt":   "one two three\n",
+		"bin.dat": "binary\x00data\n",
+	})
+
+	got := runOutput(t, "-r", root)
+	want := fmt.Sprintf(" 1  2 12 %s\n 1  3 14 %s\n 2  5 26 total\n",
+		filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt"))
+	if got != want {
+		t.Errorf("run() output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRunRecursiveDecompress(t *testing.T) {
+	gz := gzipContent(t, "one two three\n")
+	root := writeTree(t, map[string]string{
+		"a.txt":  "hello world\n",
+		"b.gz":   string(gz),
+		"bin.gz": string(gzipContent(t, "binary\x00data\n")),
+	})
+
+	got := runOutput(t, "-r", "-z", root)
+	want := fmt.Sprintf("      1       2      12      12 %s\n      1       3      14 %7d %s\n      2       5      26 %7d total\n",
+		filepath.Join(root, "a.txt"), len(gz), filepath.Join(root, "b.gz"), 12+len(gz))
+	if got != want {
+		t.Errorf("run() output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRunRecursiveUnreadableDirectory(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt":        "hello world\n",
+		"locked/b.txt": "one two three\n",
+	})
+	locked := filepath.Join(root, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(locked, 0o700) })
+	if _, err := os.ReadDir(locked); err == nil {
+		t.Skip("the directory can still be read, such as by root")
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"-r", root}, strings.NewReader(""), &stdout, &stderr)
+	if !errors.Is(err, errReported) {
+		t.Errorf("run() returned %v, want errReported", err)
+	}
+	if want := fmt.Sprintf(" 1  2 12 %s\n", filepath.Join(root, "a.txt")); stdout.String() != want {
+		t.Errorf("run() output = %q, want %q", stdout.String(), want)
+	}
+	if want := "wc: " + locked + ": Permission denied\n"; stderr.String() != want {
+		t.Errorf("run() stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestExpandFilesSymlinks(t *testing.T) {
+	root := writeTree(t, map[string]string{"dir/a.txt": "a\n", "b.txt": "b\n"})
+	for link, target := range map[string]string{"link-dir": "dir", "link-file": "b.txt", "loop": "."} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skip("symbolic links are not supported:", err)
+		}
+	}
+
+	files, failed, err := expandFiles([]string{root}, &walkOptions{recursive: true}, io.Discard)
+	if err != nil || failed {
+		t.Fatalf("expandFiles() failed: %v", err)
+	}
+	want := []string{"b.txt", "dir/a.txt", "link-file"}
+	got := make([]string, len(files))
+	for i, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[i] = filepath.ToSlash(rel)
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expandFiles() = %v, want %v", got, want)
+	}
+}